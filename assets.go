@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/golang/freetype/truetype"
+)
+
+type cachedFont struct {
+	once sync.Once
+	font *truetype.Font
+	err  error
+}
+
+type cachedLogo struct {
+	once sync.Once
+	logo []medalPart
+	err  error
+}
+
+type cachedEmblem struct {
+	once     sync.Once
+	outlines []Outline
+	err      error
+}
+
+type cachedHeightmap struct {
+	once      sync.Once
+	heightmap *heightmap
+	err       error
+}
+
+// medalAssets caches the fonts, logos and images read from disk so building many
+// medals only parses each file once. It's safe to share between goroutines.
+type medalAssets struct {
+	mutex   sync.Mutex
+	fonts   map[string]*cachedFont
+	logos   map[string]*cachedLogo
+	emblems map[flattenedSVG]*cachedEmblem
+	images  map[string]*cachedHeightmap
+
+	// smoothed heightmaps by the image and how far it was blurred
+	smoothed map[smoothedImage]*cachedHeightmap
+}
+
+// flattenedSVG is an SVG with its curves flattened to within some
+// tolerance.
+type flattenedSVG struct {
+	path      string
+	tolerance float64
+}
+
+// smoothedImage is an image blurred by some number of pixels.
+type smoothedImage struct {
+	path   string
+	radius float64
+}
+
+func newMedalAssets() *medalAssets {
+	return &medalAssets{
+		fonts:   make(map[string]*cachedFont),
+		logos:   make(map[string]*cachedLogo),
+		emblems: make(map[flattenedSVG]*cachedEmblem),
+		images:  make(map[string]*cachedHeightmap),
+
+		smoothed: make(map[smoothedImage]*cachedHeightmap),
+	}
+}
+
+// loadFont parses the TrueType font at path, which can pick a font out of a
+// collection with an index like "fonts/Noto.ttc#2".
+func loadFont(path string) (*truetype.Font, error) {
+	file, index := splitFontPath(path)
+	fontByteData, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(missingFont(file))
+		}
+		return nil, err
+	}
+
+	fontByteData, err = collectionFont(path, fontByteData, index)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedFont, err := truetype.Parse(fontByteData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse font %s: %w", path, err)
+	}
+	return parsedFont, nil
+}
+
+// importModel reads the OBJ or STL at path into a single model, picking
+// the importer from the file's extension.
+func importModel(path string) (*mesh.Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obj":
+		return importOBJ(f)
+	case ".stl":
+		return importSTL(f)
+	}
+	return nil, fmt.Errorf("unsupported model %s, must be an OBJ or STL", path)
+}
+
+// loadLogo imports the OBJ or STL at path. OBJ logos get a part for every
+// group and material in the file so each keeps its own material on the
+// medal, along with the library next to the logo that defines it.
+func loadLogo(path string) ([]medalPart, error) {
+	// Only OBJ files have groups and materials to split the logo by
+	if strings.ToLower(filepath.Ext(path)) != ".obj" {
+		model, err := importModel(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to import logo %s: %w", path, err)
+		}
+		if len(model.GetFaces()) == 0 {
+			return nil, fmt.Errorf("logo %s has no faces", path)
+		}
+		return []medalPart{{name: "logo", model: *model}}, nil
+	}
+
+	logoReader, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer logoReader.Close()
+
+	obj, err := readOBJ(logoReader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import logo %s: %w", path, err)
+	}
+
+	if len(obj.Groups) == 0 {
+		return nil, fmt.Errorf("logo %s has no faces", path)
+	}
+
+	// Libraries are found next to the logo, and the first one to define a
+	// material is where it comes from
+	libraries := make([]string, len(obj.MaterialLibraries))
+	materials := make([]map[string]*mtlMaterial, len(obj.MaterialLibraries))
+	for i, library := range obj.MaterialLibraries {
+		libraries[i] = library
+		if !filepath.IsAbs(library) {
+			libraries[i] = filepath.Join(filepath.Dir(path), library)
+		}
+		if materials[i], err = loadMTL(libraries[i]); err != nil {
+			return nil, fmt.Errorf("unable to load the material library of logo %s: %w", path, err)
+		}
+	}
+
+	parts := make([]medalPart, len(obj.Groups))
+	for i, group := range obj.Groups {
+		model, err := mesh.NewModel(group.Faces)
+		if err != nil {
+			return nil, err
+		}
+
+		name := "logo"
+		if len(obj.Groups) > 1 {
+			name = fmt.Sprintf("logo_%d", i)
+		}
+		if label := strings.Join(strings.Fields(group.Object+" "+group.Name), "_"); label != "" {
+			name = "logo_" + label
+		}
+
+		parts[i] = medalPart{name: name, material: group.Material, model: model}
+		for l, library := range materials {
+			if _, ok := library[group.Material]; ok && group.Material != "" {
+				parts[i].materialLibrary = libraries[l]
+				break
+			}
+		}
+	}
+	return parts, nil
+}
+
+// font returns the parsed font at path, reading it the first time it's
+// asked for.
+func (a *medalAssets) font(path string) (*truetype.Font, error) {
+	a.mutex.Lock()
+	entry, ok := a.fonts[path]
+	if !ok {
+		entry = &cachedFont{}
+		a.fonts[path] = entry
+	}
+	a.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.font, entry.err = loadFont(path)
+	})
+	return entry.font, entry.err
+}
+
+// logo returns the parts of the imported logo at path, reading it the first
+// time it's asked for. The parts returned are shared, so they must not be
+// modified.
+func (a *medalAssets) logo(path string) ([]medalPart, error) {
+	a.mutex.Lock()
+	entry, ok := a.logos[path]
+	if !ok {
+		entry = &cachedLogo{}
+		a.logos[path] = entry
+	}
+	a.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.logo, entry.err = loadLogo(path)
+	})
+	return entry.logo, entry.err
+}
+
+// emblem returns the outlines of the SVG at path with curves flattened to
+// within tolerance, as readSVG does, reading it the first time it's asked
+// for. The outlines returned are shared, so they must not be modified.
+func (a *medalAssets) emblem(path string, tolerance float64) ([]Outline, error) {
+	key := flattenedSVG{path: path, tolerance: tolerance}
+	a.mutex.Lock()
+	entry, ok := a.emblems[key]
+	if !ok {
+		entry = &cachedEmblem{}
+		a.emblems[key] = entry
+	}
+	a.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.outlines, entry.err = loadSVG(path, tolerance)
+	})
+	return entry.outlines, entry.err
+}
+
+// heightmap returns the luminance of the image at path, reading it the
+// first time it's asked for. The heightmap returned is shared, so it must
+// not be modified.
+func (a *medalAssets) heightmap(path string) (*heightmap, error) {
+	a.mutex.Lock()
+	entry, ok := a.images[path]
+	if !ok {
+		entry = &cachedHeightmap{}
+		a.images[path] = entry
+	}
+	a.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.heightmap, entry.err = loadHeightmap(path)
+	})
+	return entry.heightmap, entry.err
+}
+
+// smoothHeightmap returns the heightmap of the image at path blurred by
+// radius pixels, only blurring it the first time it's asked for. The
+// heightmap returned is shared, so it must not be modified.
+func (a *medalAssets) smoothHeightmap(path string, radius float64) (*heightmap, error) {
+	heights, err := a.heightmap(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := smoothedImage{path: path, radius: radius}
+	a.mutex.Lock()
+	entry, ok := a.smoothed[key]
+	if !ok {
+		entry = &cachedHeightmap{}
+		a.smoothed[key] = entry
+	}
+	a.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.heightmap = heights.smooth(radius)
+	})
+	return entry.heightmap, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// rosterRow is a single recipient read from a batch roster.
+type rosterRow struct {
+	// Line the row was found on in the roster
+	Line int
+
+	Name     string
+	Subtitle string
+
+	// Logo replaces the logo of the spec for this recipient when set
+	Logo string
+
+	// Output is the file to write this recipient's medal to when set
+	Output string
+}
+
+// batchResult is the outcome of building a single recipient's medal.
+type batchResult struct {
+	Row      rosterRow
+	Output   string
+	Duration time.Duration
+	Err      error
+}
+
+// readRoster reads a CSV of recipients with the columns name, subtitle,
+// logo and output, where only name is required. A first row starting with
+// "name" is treated as a header and skipped.
+func readRoster(in io.Reader) ([]rosterRow, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	rows := make([]rosterRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if len(rows) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+
+		if len(record) > 4 {
+			return nil, fmt.Errorf("line %d: expected at most 4 columns (name, subtitle, logo, output), got %d", line, len(record))
+		}
+
+		fields := make([]string, 4)
+		copy(fields, record)
+
+		row := rosterRow{
+			Line:     line,
+			Name:     strings.TrimSpace(fields[0]),
+			Subtitle: strings.TrimSpace(fields[1]),
+			Logo:     strings.TrimSpace(fields[2]),
+			Output:   strings.TrimSpace(fields[3]),
+		}
+
+		if row.Name == "" {
+			return nil, fmt.Errorf("line %d: name is required", line)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("roster has no recipients")
+	}
+
+	return rows, nil
+}
+
+// defaultBatchTemplate writes the recipient's name along the top of the
+// medal and their subtitle along the bottom.
+func defaultBatchTemplate() medalSpec {
+	spec := defaultMedalSpec()
+
+	name := defaultTextSpec()
+	name.Text = "{name}"
+	name.Layout = "top-arc"
+
+	subtitle := defaultTextSpec()
+	subtitle.Text = "{subtitle}"
+	subtitle.Layout = "bottom-arc"
+
+	spec.Text = []textSpec{name, subtitle}
+	return spec
+}
+
+// rosterFileName turns a recipient's name into something safe to use as a
+// file name.
+func rosterFileName(name string) string {
+	fileName := strings.Builder{}
+	lastWasDash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			fileName.WriteRune(r)
+			lastWasDash = false
+		} else if !lastWasDash && fileName.Len() > 0 {
+			fileName.WriteRune('-')
+			lastWasDash = true
+		}
+	}
+	return strings.TrimSuffix(fileName.String(), "-")
+}
+
+// specForRow fills in the template's {name} and {subtitle} placeholders for
+// the recipient, on the front and the reverse. Text that ends up blank is
+// left off the medal.
+func specForRow(template medalSpec, row rosterRow, outDir string) medalSpec {
+	spec := template
+
+	replacer := strings.NewReplacer("{name}", row.Name, "{subtitle}", row.Subtitle)
+	fillText := func(texts []textSpec) []textSpec {
+		filled := make([]textSpec, 0, len(texts))
+		for _, text := range texts {
+			text.Text = replacer.Replace(text.Text)
+			if strings.TrimSpace(text.Text) != "" {
+				filled = append(filled, text)
+			}
+		}
+		return filled
+	}
+
+	spec.Text = fillText(template.Text)
+	if template.Reverse != nil {
+		reverse := *template.Reverse
+		reverse.Text = fillText(template.Reverse.Text)
+		spec.Reverse = &reverse
+	}
+
+	if row.Logo != "" {
+		logo := defaultLogoSpec()
+		if template.Logo != nil {
+			logo = *template.Logo
+		}
+		logo.Path = row.Logo
+
+		// Meshes are scaled and SVGs are sized to their longest side, so
+		// the template's scale is only kept for the same kind of logo
+		if template.Logo == nil || template.Logo.isSVG() != logo.isSVG() {
+			logo.Scale = 0
+		}
+		spec.Logo = &logo
+	}
+
+	// Recipients without their own output are saved in the template's
+	// format, otherwise the format comes from their output's extension
+	output := row.Output
+	if output == "" {
+		if spec.Output.Format == "" {
+			spec.applyDefaults()
+		}
+		fileName := rosterFileName(row.Name)
+		if fileName == "" {
+			// Names without a letter or digit in them fall back on the
+			// line they're on, so they don't all share one hidden file
+			fileName = fmt.Sprintf("medal-%d", row.Line)
+		}
+		output = fileName + formatExtension(spec.Output.Format)
+	} else {
+		spec.Output.Format = ""
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(outDir, output)
+	}
+	spec.Output.Path = output
+	spec.applyDefaults()
+
+	return spec
+}
+
+// runBatch builds a medal for every row of the roster using a bounded pool
+// of workers. Fonts and logos are shared across every medal in the batch.
+// Results come back in the same order as the roster.
+func runBatch(rows []rosterRow, template medalSpec, outDir string, workers int) []batchResult {
+	results := make([]batchResult, len(rows))
+	assets := newMedalAssets()
+
+	specs := make([]medalSpec, len(rows))
+	seenOutputs := make(map[string]int)
+	for i, row := range rows {
+		specs[i] = specForRow(template, row, outDir)
+		results[i] = batchResult{Row: row, Output: specs[i].Output.Path}
+
+		if previous, ok := seenOutputs[specs[i].Output.Path]; ok {
+			results[i].Err = fmt.Errorf("output %s is already used by line %d", specs[i].Output.Path, rows[previous].Line)
+			continue
+		}
+		seenOutputs[specs[i].Output.Path] = i
+	}
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				results[i].Err = buildAndSave(specs[i], assets)
+				results[i].Duration = time.Since(start)
+			}
+		}()
+	}
+
+	for i := range rows {
+		if results[i].Err == nil {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func buildAndSave(spec medalSpec, assets *medalAssets) error {
+	parts, err := buildMedal(spec, assets)
+	if err != nil {
+		return err
+	}
+	return saveMedal(parts, spec.Output)
+}
+
+// writeBatchReport writes a CSV with a line for every recipient of the
+// batch and whether or not their medal was built.
+func writeBatchReport(out io.Writer, results []batchResult) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"line", "name", "output", "status", "duration", "error"}); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		status := "ok"
+		message := ""
+		if result.Err != nil {
+			status = "failed"
+			message = result.Err.Error()
+		}
+
+		err := writer.Write([]string{
+			fmt.Sprint(result.Row.Line),
+			result.Row.Name,
+			result.Output,
+			status,
+			result.Duration.String(),
+			message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRoster(t *testing.T) {
+	rows, err := readRoster(strings.NewReader(`name,subtitle,logo,output
+Aleatha, Singleton
+# skipped
+"Doe, Jane",Employee of the Year,logo.obj,jane.obj
+`))
+
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, rosterRow{Line: 2, Name: "Aleatha", Subtitle: "Singleton"}, rows[0])
+		assert.Equal(t, rosterRow{
+			Line:     4,
+			Name:     "Doe, Jane",
+			Subtitle: "Employee of the Year",
+			Logo:     "logo.obj",
+			Output:   "jane.obj",
+		}, rows[1])
+	}
+}
+
+func TestReadRosterRequiresName(t *testing.T) {
+	_, err := readRoster(strings.NewReader("Aleatha\n,Singleton\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2")
+	}
+}
+
+func TestReadRosterRejectsExtraColumns(t *testing.T) {
+	_, err := readRoster(strings.NewReader("a,b,c,d,e\n"))
+	assert.Error(t, err)
+}
+
+func TestRosterFileName(t *testing.T) {
+	assert.Equal(t, "doe-jane", rosterFileName("Doe, Jane"))
+	assert.Equal(t, "zoë", rosterFileName(" Zoë! "))
+}
+
+func TestSpecForRow(t *testing.T) {
+	spec := specForRow(defaultBatchTemplate(), rosterRow{Name: "Aleatha", Logo: "logo.obj"}, "medals")
+
+	if assert.Len(t, spec.Text, 1) {
+		assert.Equal(t, "Aleatha", spec.Text[0].Text)
+	}
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, "logo.obj", spec.Logo.Path)
+	}
+	assert.Equal(t, filepath.Join("medals", "aleatha.obj"), spec.Output.Path)
+	assert.Equal(t, "obj", spec.Output.Format)
+}
+
+func TestSpecForRowNamesFilesWithoutLetters(t *testing.T) {
+	first := specForRow(defaultBatchTemplate(), rosterRow{Line: 2, Name: "!!!"}, "medals")
+	second := specForRow(defaultBatchTemplate(), rosterRow{Line: 3, Name: "—"}, "medals")
+
+	assert.Equal(t, filepath.Join("medals", "medal-2.obj"), first.Output.Path)
+	assert.Equal(t, filepath.Join("medals", "medal-3.obj"), second.Output.Path)
+}
+
+func TestSpecForRowFillsReverse(t *testing.T) {
+	template := defaultBatchTemplate()
+	date := defaultTextSpec()
+	date.Text = "2026"
+	name := defaultTextSpec()
+	name.Text = "Awarded to {name}"
+	template.Reverse = &reverseSpec{Text: []textSpec{date, name}}
+
+	spec := specForRow(template, rosterRow{Name: "Aleatha"}, "medals")
+	if assert.NotNil(t, spec.Reverse) && assert.Len(t, spec.Reverse.Text, 2) {
+		assert.Equal(t, "2026", spec.Reverse.Text[0].Text)
+		assert.Equal(t, "Awarded to Aleatha", spec.Reverse.Text[1].Text)
+	}
+
+	// Every recipient gets their own reverse
+	assert.Equal(t, "Awarded to {name}", template.Reverse.Text[1].Text)
+}
+
+func TestRunBatchRejectsDuplicateOutputs(t *testing.T) {
+	template := defaultBatchTemplate()
+	template.Body.Radius = -1
+
+	results := runBatch([]rosterRow{
+		{Line: 1, Name: "Aleatha"},
+		{Line: 2, Name: "aleatha"},
+	}, template, t.TempDir(), 2)
+
+	if assert.Len(t, results, 2) {
+		var specErrs specErrors
+		assert.True(t, errors.As(results[0].Err, &specErrs))
+		if assert.Error(t, results[1].Err) {
+			assert.Contains(t, results[1].Err.Error(), "already used by line 1")
+		}
+	}
+}
+
+func TestSpecForRowScalesLogosByTheirKind(t *testing.T) {
+	template := defaultBatchTemplate()
+	template.applyDefaults()
+
+	spec := specForRow(template, rosterRow{Name: "Aleatha", Logo: "emblem.svg"}, "medals")
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, defaultSVGLogoScale, spec.Logo.Scale)
+	}
+
+	// A mesh logo's scale means nothing to an SVG
+	logo := defaultLogoSpec()
+	logo.Path = "logo.obj"
+	template.Logo = &logo
+	spec = specForRow(template, rosterRow{Name: "Aleatha", Logo: "emblem.svg"}, "medals")
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, defaultSVGLogoScale, spec.Logo.Scale)
+	}
+
+	// The same kind of logo keeps the template's scale
+	logo.Scale = .1
+	spec = specForRow(template, rosterRow{Name: "Aleatha", Logo: "other.stl"}, "medals")
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, .1, spec.Logo.Scale)
+	}
+}
+
+func TestSpecForRowKeepsTemplateFormat(t *testing.T) {
+	template := defaultBatchTemplate()
+	template.Output.Format = "stl-ascii"
+
+	spec := specForRow(template, rosterRow{Name: "Aleatha"}, "medals")
+	assert.Equal(t, filepath.Join("medals", "aleatha.stl"), spec.Output.Path)
+	assert.Equal(t, "stl-ascii", spec.Output.Format)
+
+	spec = specForRow(template, rosterRow{Name: "Aleatha", Output: "aleatha.obj"}, "medals")
+	assert.Equal(t, "obj", spec.Output.Format)
+}
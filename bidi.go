@@ -0,0 +1,269 @@
+package main
+
+import (
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/unicode/norm"
+)
+
+// textClusters normalizes the text so letters written with combining marks
+// use the font's precomposed glyphs where it can, then splits it into
+// grapheme clusters, which are what a reader sees as a single letter.
+func textClusters(text string) []string {
+	clusters := make([]string, 0, len(text))
+	graphemes := uniseg.NewGraphemes(norm.NFC.String(text))
+	for graphemes.Next() {
+		clusters = append(clusters, graphemes.Str())
+	}
+	return clusters
+}
+
+// arabicForms are the presentation forms of every Arabic letter that
+// changes shape depending on the letters it joins to, in the order
+// isolated, final, initial and medial. Letters that only join to the letter
+// before them have no initial or medial forms.
+var arabicForms = map[rune][]rune{
+	'آ': {0xFE81, 0xFE82},
+	'أ': {0xFE83, 0xFE84},
+	'ؤ': {0xFE85, 0xFE86},
+	'إ': {0xFE87, 0xFE88},
+	'ئ': {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	'ا': {0xFE8D, 0xFE8E},
+	'ب': {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	'ة': {0xFE93, 0xFE94},
+	'ت': {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	'ث': {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	'ج': {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	'ح': {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	'خ': {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	'د': {0xFEA9, 0xFEAA},
+	'ذ': {0xFEAB, 0xFEAC},
+	'ر': {0xFEAD, 0xFEAE},
+	'ز': {0xFEAF, 0xFEB0},
+	'س': {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	'ش': {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	'ص': {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	'ض': {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	'ط': {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	'ظ': {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	'ع': {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	'غ': {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	'ف': {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	'ق': {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	'ك': {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	'ل': {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	'م': {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	'ن': {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	'ه': {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	'و': {0xFEED, 0xFEEE},
+	'ى': {0xFEEF, 0xFEF0},
+	'ي': {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+
+	// Letters Persian and Urdu add
+	'پ': {0xFB56, 0xFB57, 0xFB58, 0xFB59},
+	'چ': {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D},
+	'ژ': {0xFB8A, 0xFB8B},
+	'ک': {0xFB8E, 0xFB8F, 0xFB90, 0xFB91},
+	'گ': {0xFB92, 0xFB93, 0xFB94, 0xFB95},
+	'ی': {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF},
+}
+
+// lamAlefForms are the ligatures lam makes with the alef that follows it,
+// isolated and final.
+var lamAlefForms = map[rune][2]rune{
+	'آ': {0xFEF5, 0xFEF6},
+	'أ': {0xFEF7, 0xFEF8},
+	'إ': {0xFEF9, 0xFEFA},
+	'ا': {0xFEFB, 0xFEFC},
+}
+
+const (
+	arabicLam     = 'ل'
+	arabicTatweel = 'ـ'
+)
+
+// joinsBefore is whether the letter connects to the letter before it.
+func joinsBefore(r rune) bool {
+	_, ok := arabicForms[r]
+	return ok || r == arabicTatweel
+}
+
+// joinsAfter is whether the letter connects to the letter after it.
+func joinsAfter(r rune) bool {
+	return len(arabicForms[r]) == 4 || r == arabicTatweel
+}
+
+// firstRune is the rune a grapheme cluster is built on.
+func firstRune(cluster string) rune {
+	for _, r := range cluster {
+		return r
+	}
+	return 0
+}
+
+// shapeArabic swaps every Arabic letter for the form it takes given the
+// letters around it, since fonts can't do that themselves without the
+// OpenType tables freetype doesn't read. Forms the font doesn't have are
+// left as they were. Clusters are expected in the order they're written.
+func shapeArabic(clusters []string, hasGlyph func(rune) bool) []string {
+	shaped := make([]string, 0, len(clusters))
+	for i := 0; i < len(clusters); i++ {
+		letter := firstRune(clusters[i])
+		if !joinsBefore(letter) {
+			shaped = append(shaped, clusters[i])
+			continue
+		}
+
+		joinedBefore := i > 0 && joinsAfter(firstRune(clusters[i-1]))
+
+		// Lam followed by alef becomes a single ligature
+		if letter == arabicLam && i+1 < len(clusters) {
+			if ligature, ok := lamAlefForms[firstRune(clusters[i+1])]; ok {
+				form := ligature[0]
+				if joinedBefore {
+					form = ligature[1]
+				}
+				if hasGlyph(form) {
+					// Marks on both letters stay with the ligature
+					marks := clusters[i][len(string(letter)):] + clusters[i+1][len(string(firstRune(clusters[i+1]))):]
+					shaped = append(shaped, string(form)+marks)
+					i++
+					continue
+				}
+			}
+		}
+
+		joinedAfter := joinsAfter(letter) && i+1 < len(clusters) && joinsBefore(firstRune(clusters[i+1]))
+
+		forms := arabicForms[letter]
+		form := 0
+		switch {
+		case joinedBefore && joinedAfter:
+			form = 3
+		case joinedAfter:
+			form = 2
+		case joinedBefore:
+			form = 1
+		}
+
+		if form < len(forms) && hasGlyph(forms[form]) {
+			shaped = append(shaped, string(forms[form])+clusters[i][len(string(letter)):])
+		} else {
+			shaped = append(shaped, clusters[i])
+		}
+	}
+	return shaped
+}
+
+// clusterDirection is the strongest direction any rune of the cluster has.
+func clusterDirection(cluster string) bidi.Class {
+	for _, r := range cluster {
+		properties, _ := bidi.LookupRune(r)
+		switch class := properties.Class(); class {
+		case bidi.L, bidi.R, bidi.EN, bidi.AN:
+			return class
+		case bidi.AL:
+			return bidi.R
+		}
+	}
+	return bidi.ON
+}
+
+// visualOrder rearranges clusters from the order they're written in to the
+// order they're drawn in left to right, so right to left scripts like
+// Hebrew and Arabic read correctly. This follows the parts of the Unicode
+// bidirectional algorithm a single line of a name needs: the paragraph
+// takes the direction of its first strong letter, numbers always read left
+// to right, and punctuation and spaces take the direction of the letters
+// around them.
+func visualOrder(clusters []string) []string {
+	classes := make([]bidi.Class, len(clusters))
+	hasRTL := false
+	for i, cluster := range clusters {
+		classes[i] = clusterDirection(cluster)
+		hasRTL = hasRTL || classes[i] == bidi.R || classes[i] == bidi.AN
+	}
+	if !hasRTL {
+		return clusters
+	}
+
+	paragraph := 0
+	for _, class := range classes {
+		if class == bidi.L {
+			break
+		}
+		if class == bidi.R {
+			paragraph = 1
+			break
+		}
+	}
+
+	// Left to right letters get an even level and right to left letters an
+	// odd one, higher than the paragraph's when they go against it
+	ltr := paragraph + (paragraph % 2)
+	rtl := 1
+
+	// The direction of the closest letter on each side, with numbers
+	// counting as right to left for the punctuation between them
+	strongest := func(from, step int) bidi.Class {
+		for i := from; i >= 0 && i < len(classes); i += step {
+			switch classes[i] {
+			case bidi.L:
+				return bidi.L
+			case bidi.R, bidi.EN, bidi.AN:
+				return bidi.R
+			}
+		}
+		return bidi.ON
+	}
+
+	levels := make([]int, len(clusters))
+	maxLevel := 0
+	for i, class := range classes {
+		switch class {
+		case bidi.L:
+			levels[i] = ltr
+		case bidi.R:
+			levels[i] = rtl
+		case bidi.EN, bidi.AN:
+			// Numbers read left to right, nested inside right to left text
+			// when that's what's around them
+			levels[i] = ltr
+			if previous := strongest(i-1, -1); paragraph == 1 || previous == bidi.R {
+				levels[i] = 2
+			}
+		default:
+			before, after := strongest(i-1, -1), strongest(i+1, 1)
+			levels[i] = paragraph
+			if before == after && before == bidi.L {
+				levels[i] = ltr
+			} else if before == after && before == bidi.R {
+				levels[i] = rtl
+			}
+		}
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+
+	// Reverse every run at each level, from the highest level down
+	ordered := append([]string(nil), clusters...)
+	for level := maxLevel; level >= 1; level-- {
+		for start := 0; start < len(ordered); {
+			if levels[start] < level {
+				start++
+				continue
+			}
+			end := start
+			for end < len(ordered) && levels[end] >= level {
+				end++
+			}
+			for a, b := start, end-1; a < b; a, b = a+1, b-1 {
+				ordered[a], ordered[b] = ordered[b], ordered[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			start = end
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextClustersNormalizes(t *testing.T) {
+	// The e and its diaeresis become the single precomposed ë
+	assert.Equal(t, []string{"Z", "o", "ë"}, textClusters("Zoë"))
+
+	// Letters with no precomposed form keep their marks in one cluster
+	assert.Equal(t, []string{"q́", "a"}, textClusters("q́a"))
+
+	assert.Equal(t, []string{"Ł", "u", "k", "a", "s", "z"}, textClusters("Łukasz"))
+}
+
+func TestReverseKeepsMarksOnTheirLetters(t *testing.T) {
+	assert.Equal(t, "ëoZ", Reverse("Zoë"))
+	assert.Equal(t, "zsakuŁ", Reverse("Łukasz"))
+	assert.Equal(t, " notelgniS ", Reverse(" Singleton "))
+}
+
+func TestVisualOrder(t *testing.T) {
+	order := func(text string) string {
+		result := ""
+		for _, cluster := range visualOrder(textClusters(text)) {
+			result += cluster
+		}
+		return result
+	}
+
+	assert.Equal(t, "Aleatha", order("Aleatha"))
+	assert.Equal(t, "םולש", order("שלום"))
+
+	// Left to right text keeps its order around a right to left name
+	assert.Equal(t, "Dana ןהכ", order("Dana כהן"))
+
+	// Numbers read left to right inside right to left text
+	assert.Equal(t, "12 בא", order("אב 12"))
+}
+
+func TestShapeArabicPicksJoiningForms(t *testing.T) {
+	everyGlyph := func(rune) bool { return true }
+
+	// Seen starts the word, lam and alef join into one ligature, and meem
+	// stands alone since alef doesn't join to the letter after it
+	assert.Equal(t, []string{"ﺳ", "ﻼ", "ﻡ"}, shapeArabic(textClusters("سلام"), everyGlyph))
+
+	// Beh in the middle of a word joins on both sides
+	assert.Equal(t, []string{"ﺑ", "ﺒ", "ﺐ"}, shapeArabic(textClusters("ببب"), everyGlyph))
+
+	// Forms the font doesn't have are left alone
+	noGlyphs := func(rune) bool { return false }
+	assert.Equal(t, []string{"س", "ل", "ا", "م"}, shapeArabic(textClusters("سلام"), noGlyphs))
+}
+
+func TestTextToShapeReportsMissingGlyphs(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The zero width joiner is invisible so it isn't missing
+	text, err := TextToShape("Z\u200do☃o", parsedFont, defaultCurveTolerance)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{'☃'}, text.Missing)
+	assert.Len(t, text.Glyphs, 4)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/golang/freetype/truetype"
+)
+
+// command is a single subcommand of the medal tool, ran like
+// `medal-generation <name> [flags]`.
+type command struct {
+	name        string
+	description string
+	run         func(args []string, out io.Writer) error
+}
+
+func commands() []command {
+	return []command{
+		{
+			name:        "generate",
+			description: "build a medal and save it to disk",
+			run:         runGenerate,
+		},
+		{
+			name:        "batch",
+			description: "build a medal for every recipient in a CSV roster",
+			run:         runBatchCommand,
+		},
+		{
+			name:        "preview-text",
+			description: "build a single line of extruded text and save it to disk",
+			run:         runPreviewText,
+		},
+		{
+			name:        "inspect",
+			description: "print statistics about OBJ and STL files",
+			run:         runInspect,
+		},
+	}
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintln(out, "usage: medal-generation <command> [flags]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(out, "  %-14s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "run `medal-generation <command> -h` for the flags of a command")
+}
+
+// run dispatches the arguments to the matching subcommand.
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		usage(out)
+		return errors.New("no command specified")
+	}
+
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(out)
+		return nil
+	}
+
+	for _, c := range commands() {
+		if c.name == args[0] {
+			err := c.run(args[1:], out)
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	usage(out)
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	return flags
+}
+
+// stringsFlag is a flag that can be given more than once, collecting every
+// value in the order they were given.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runGenerate(args []string, out io.Writer) error {
+	spec := defaultMedalSpec()
+	topText := defaultTextSpec()
+	topText.Text = "Aleatha"
+	bottomText := defaultTextSpec()
+	bottomText.Text = " Singleton "
+	bottomText.Layout = "bottom-arc"
+	logo := defaultLogoSpec()
+	relief := defaultReliefSpec()
+	reverse := reverseSpec{}
+	reverseText := defaultTextSpec()
+	reverseText.Layout = "block"
+	reverseLogo := defaultLogoSpec()
+	reverseLogo.Scale = 0
+
+	flags := newFlagSet("generate", out)
+	specPath := flags.String("spec", "", "YAML or JSON medal spec to build, can only be combined with -out, -format and -units")
+	flags.StringVar(&spec.Body.Shape, "shape", spec.Body.Shape, fmt.Sprintf("shape of the medal (%s)", strings.Join(medalShapes, ", ")))
+	flags.Float64Var(&spec.Body.Radius, "radius", spec.Body.Radius, "radius of the medal")
+	flags.IntVar(&spec.Body.Sides, "sides", spec.Body.Sides, "sides of a polygon shaped medal")
+	flags.IntVar(&spec.Body.Points, "points", spec.Body.Points, "points of a star shaped medal")
+	flags.Float64Var(&spec.Body.InnerRadius, "inner-radius", 0, "radius of the corners between the points of a star shaped medal, half the radius when 0")
+	flags.StringVar(&spec.Body.Path, "shape-path", spec.Body.Path, "SVG whose largest shape is the outline of a custom shaped medal")
+	flags.Float64Var(&spec.Body.Thickness, "thickness", spec.Body.Thickness, "thickness of the medal")
+	flags.Float64Var(&spec.Body.Impression, "impression", spec.Body.Impression, "depth of the design face below the rim")
+	flags.Float64Var(&spec.Body.Rim.Border, "rim", spec.Body.Rim.Border, "width of the rim around the design face")
+	flags.StringVar(&spec.Body.Edge.Profile, "edge", spec.Body.Edge.Profile, fmt.Sprintf("profile of the medal's side (%s)", strings.Join(edgeProfileNames(), ", ")))
+	flags.Float64Var(&spec.Body.Edge.Size, "edge-size", spec.Body.Edge.Size, "how far the side bulges out, how far an ogee bows out and curls back in, or how far a chamfer, round-over or stepped edge cuts in, picked for the profile when 0")
+	flags.StringVar(&topText.Text, "top-text", topText.Text, "text along the top of the medal, empty for none")
+	flags.Float64Var(&topText.Scale, "top-text-scale", topText.Scale, "scale of the top text")
+	flags.StringVar(&topText.Style, "top-text-style", topText.Style, "emboss or engrave the top text")
+	flags.StringVar(&bottomText.Text, "bottom-text", bottomText.Text, "text along the bottom of the medal, empty for none")
+	flags.Float64Var(&bottomText.Scale, "bottom-text-scale", bottomText.Scale, "scale of the bottom text")
+	flags.StringVar(&bottomText.Style, "bottom-text-style", bottomText.Style, "emboss or engrave the bottom text")
+	font := flags.String("font", defaultFont, "TrueType font used for all text, with #N after a collection to pick its Nth font")
+	var fallbackFonts stringsFlag
+	flags.Var(&fallbackFonts, "fallback-font", "font to draw characters the font is missing from, can be given more than once")
+	curveTolerance := flags.Float64("curve-tolerance", 0, "furthest a flattened letter curve can stray from the real curve, taken from -tolerance when 0")
+	flags.Float64Var(&spec.Quality.Tolerance, "tolerance", spec.Quality.Tolerance, "furthest a line can stray from the curve it stands in for, in -units")
+	flags.Float64Var(&spec.Quality.MaxAngle, "max-angle", spec.Quality.MaxAngle, "most a curve can turn in degrees over a single line")
+	flags.StringVar(&logo.Path, "logo", logo.Path, "OBJ, STL or SVG file to place in the center of the medal, empty for none")
+	flags.Float64Var(&logo.Scale, "logo-scale", 0, fmt.Sprintf("scale applied to the logo mesh (default %g), or the length of an SVG logo's longest side (default %g)", logo.Scale, defaultSVGLogoScale))
+	flags.StringVar(&logo.Style, "logo-style", logo.Style, "emboss or engrave an SVG logo")
+	flags.Float64Var(&logo.Depth, "logo-depth", 0, "how far an SVG logo rises above or sinks below the face, the impression when 0")
+	flags.Float64Var(&logo.Height, "logo-height", logo.Height, "height of the logo's center above the bottom of the medal")
+	flags.StringVar(&relief.Path, "relief", relief.Path, "PNG or JPEG to raise out of the design face, empty for none")
+	flags.Float64Var(&relief.Smoothing, "relief-smoothing", relief.Smoothing, "pixels to blur the relief image by")
+	flags.IntVar(&relief.MaxTriangles, "relief-max-triangles", relief.MaxTriangles, "most triangles the relief can be made of")
+	flags.BoolVar(&relief.Invert, "relief-invert", relief.Invert, "raise dark pixels instead of bright ones")
+	flags.StringVar(&reverseText.Text, "reverse-text", reverseText.Text, "text in the middle of the reverse, which makes the medal double sided")
+	flags.StringVar(&reverseLogo.Path, "reverse-logo", reverseLogo.Path, "SVG file to place in the center of the reverse, which makes the medal double sided")
+	flags.Float64Var(&reverse.Impression, "reverse-impression", 0, "depth of the reverse face below its rim, the impression when 0")
+	outPath := flags.String("out", spec.Output.Path, "path to write the medal to")
+	format := flags.String("format", "", fmt.Sprintf("format to save the medal as (%s), taken from the extension of -out when empty", strings.Join(outputFormats, ", ")))
+	units := flags.String("units", spec.Output.Units, fmt.Sprintf("units the medal is modelled in (%s), recorded in STL headers", strings.Join(outputUnits, ", ")))
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	outSet := false
+	unitsSet := false
+	designFlags := make([]string, 0)
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "out":
+			outSet = true
+		case "units":
+			unitsSet = true
+		case "spec", "format":
+		default:
+			designFlags = append(designFlags, "-"+f.Name)
+		}
+	})
+
+	if *specPath != "" {
+		if len(designFlags) > 0 {
+			return fmt.Errorf("%s can't be combined with -spec", strings.Join(designFlags, ", "))
+		}
+
+		loaded, err := loadSpec(*specPath)
+		if err != nil {
+			return err
+		}
+		spec = loaded
+	} else {
+		topText.Font = *font
+		bottomText.Font = *font
+		topText.FallbackFonts = fallbackFonts
+		bottomText.FallbackFonts = fallbackFonts
+		topText.CurveTolerance = *curveTolerance
+		bottomText.CurveTolerance = *curveTolerance
+		for _, text := range []textSpec{topText, bottomText} {
+			if text.Text != "" {
+				spec.Text = append(spec.Text, text)
+			}
+		}
+
+		if logo.Path != "" {
+			spec.Logo = &logo
+		}
+		if relief.Path != "" {
+			spec.Relief = &relief
+		}
+
+		if reverseText.Text != "" || reverseLogo.Path != "" {
+			reverseText.Font = *font
+			reverseText.FallbackFonts = fallbackFonts
+			reverseText.CurveTolerance = *curveTolerance
+			if reverseText.Text != "" {
+				reverse.Text = append(reverse.Text, reverseText)
+			}
+			if reverseLogo.Path != "" {
+				reverse.Logo = &reverseLogo
+			}
+			spec.Reverse = &reverse
+		}
+	}
+
+	if outSet || *specPath == "" {
+		spec.Output.Path = *outPath
+		spec.Output.Format = ""
+	}
+	if *format != "" {
+		spec.Output.Format = *format
+	}
+	if unitsSet || *specPath == "" {
+		spec.Output.Units = *units
+	}
+	spec.applyDefaults()
+
+	parts, err := buildMedal(spec, newMedalAssets())
+	if err != nil {
+		return err
+	}
+
+	return saveMedal(parts, spec.Output)
+}
+
+func runBatchCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("batch", out)
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: medal-generation batch [flags] roster.csv")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Each row of the roster is: name, subtitle, logo (optional), output (optional)")
+		fmt.Fprintln(out)
+		flags.PrintDefaults()
+	}
+	specPath := flags.String("spec", "", "YAML or JSON medal spec used for every medal, where {name} and {subtitle} in text are replaced per recipient")
+	outDir := flags.String("out-dir", ".", "directory medals are written to")
+	workers := flags.Int("workers", runtime.NumCPU(), "how many medals to build at once")
+	reportPath := flags.String("report", "", "CSV file to write the outcome of every recipient to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("batch requires exactly one roster file")
+	}
+
+	if *workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", *workers)
+	}
+
+	template := defaultBatchTemplate()
+	if *specPath != "" {
+		loaded, err := loadSpec(*specPath)
+		if err != nil {
+			return err
+		}
+		template = loaded
+	}
+
+	rosterPath := flags.Arg(0)
+	rosterFile, err := os.Open(rosterPath)
+	if err != nil {
+		return err
+	}
+	rows, err := readRoster(rosterFile)
+	rosterFile.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", rosterPath, err)
+	}
+
+	for i := range rows {
+		if rows[i].Logo != "" && !filepath.IsAbs(rows[i].Logo) {
+			rows[i].Logo = filepath.Join(filepath.Dir(rosterPath), rows[i].Logo)
+		}
+	}
+
+	if err := os.MkdirAll(*outDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	results := runBatch(rows, template, *outDir, *workers)
+
+	failures := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failures++
+			fmt.Fprintf(out, "line %d (%s): %s\n", result.Row.Line, result.Row.Name, result.Err)
+		}
+	}
+	fmt.Fprintf(out, "%d succeeded, %d failed\n", len(results)-failures, failures)
+
+	if *reportPath != "" {
+		reportFile, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		err = writeBatchReport(reportFile, results)
+		reportFile.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d medals failed", failures, len(results))
+	}
+	return nil
+}
+
+func runPreviewText(args []string, out io.Writer) error {
+	flags := newFlagSet("preview-text", out)
+	text := flags.String("text", "Aleatha", "text to build")
+	scale := flags.Float64("scale", .4, "scale of the text")
+	extrusion := flags.Float64("extrusion", 0.1, "how far the text is extruded")
+	font := flags.String("font", defaultFont, "TrueType font to write the text with, with #N after a collection to pick its Nth font")
+	var fallbackFonts stringsFlag
+	flags.Var(&fallbackFonts, "fallback-font", "font to draw characters the font is missing from, can be given more than once")
+	curveTolerance := flags.Float64("curve-tolerance", defaultCurveTolerance, "furthest a flattened letter curve can stray from the real curve")
+	outPath := flags.String("out", "text.obj", "path to write the text to")
+	format := flags.String("format", "", fmt.Sprintf("format to save the text as (%s), taken from the extension of -out when empty", strings.Join(outputFormats, ", ")))
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if strings.TrimSpace(*text) == "" {
+		return errors.New("text is required")
+	}
+
+	if *scale <= 0 {
+		return fmt.Errorf("scale must be greater than 0, got %g", *scale)
+	}
+
+	if *extrusion <= 0 {
+		return fmt.Errorf("extrusion must be greater than 0, got %g", *extrusion)
+	}
+
+	if *curveTolerance <= 0 {
+		return fmt.Errorf("curve tolerance must be greater than 0, got %g", *curveTolerance)
+	}
+
+	if *font == "" {
+		return errors.New("font path is required")
+	}
+
+	if *outPath == "" {
+		return errors.New("an output path is required")
+	}
+
+	output := defaultMedalSpec().Output
+	output.Path = *outPath
+	output.Format = *format
+	if output.Format == "" {
+		output.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(output.Path)), ".")
+	}
+	if !containsString(outputFormats, output.Format) {
+		return fmt.Errorf("unsupported format %q, must be one of %s", output.Format, strings.Join(outputFormats, ", "))
+	}
+
+	parsedFont, err := loadFont(*font)
+	if err != nil {
+		return err
+	}
+	fallbacks := make([]*truetype.Font, len(fallbackFonts))
+	for i, path := range fallbackFonts {
+		if fallbacks[i], err = loadFont(path); err != nil {
+			return err
+		}
+	}
+
+	model, err := TextToModel(*text, parsedFont, *curveTolerance, *scale, *extrusion, straightTextLayout, fallbacks...)
+	if err != nil {
+		return err
+	}
+
+	return saveMedal([]medalPart{{name: "text", model: model}}, output)
+}
+
+// modelBounds finds the axis aligned bounding box of all vertices in the
+// model.
+func modelBounds(m mesh.Model) (vector.Vector3, vector.Vector3) {
+	min := vector.NewVector3(math.Inf(1), math.Inf(1), math.Inf(1))
+	max := vector.NewVector3(math.Inf(-1), math.Inf(-1), math.Inf(-1))
+	for _, face := range m.GetFaces() {
+		for _, v := range face.GetVertices() {
+			min = vector.NewVector3(math.Min(min.X(), v.X()), math.Min(min.Y(), v.Y()), math.Min(min.Z(), v.Z()))
+			max = vector.NewVector3(math.Max(max.X(), v.X()), math.Max(max.Y(), v.Y()), math.Max(max.Z(), v.Z()))
+		}
+	}
+	return min, max
+}
+
+func runInspect(args []string, out io.Writer) error {
+	flags := newFlagSet("inspect", out)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return errors.New("inspect requires at least one OBJ or STL file")
+	}
+
+	for _, path := range flags.Args() {
+		model, err := importModel(path)
+		if err != nil {
+			return fmt.Errorf("unable to import %s: %w", path, err)
+		}
+
+		fmt.Fprintf(out, "%s\n", path)
+		fmt.Fprintf(out, "  faces:  %d\n", len(model.GetFaces()))
+		if len(model.GetFaces()) == 0 {
+			continue
+		}
+
+		min, max := modelBounds(*model)
+		size := max.Sub(min)
+		fmt.Fprintf(out, "  min:    (%g, %g, %g)\n", min.X(), min.Y(), min.Z())
+		fmt.Fprintf(out, "  max:    (%g, %g, %g)\n", max.X(), max.Y(), max.Z())
+		fmt.Fprintf(out, "  size:   (%g, %g, %g)\n", size.X(), size.Y(), size.Z())
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRequiresCommand(t *testing.T) {
+	assert.Error(t, run([]string{}, ioutil.Discard))
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	err := run([]string{"not-a-command"}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not-a-command")
+	}
+}
+
+func TestGenerateValidatesOptions(t *testing.T) {
+	err := run([]string{"generate", "-radius", "-1"}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "radius")
+	}
+
+	err = run([]string{"generate", "-impression", "0.5"}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "impression")
+	}
+
+	err = run([]string{"generate", "-reverse-logo", "logo.obj"}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "reverse.logo.path: only SVG logos can go on the reverse")
+	}
+
+	err = run([]string{"generate", "-top-text", "Aleatha", "-font", ""}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "text[0].font: font path is required")
+	}
+
+	err = run([]string{"preview-text", "-font", ""}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "font path is required")
+	}
+}
+
+func TestGenerateRejectsDesignFlagsWithSpec(t *testing.T) {
+	err := run([]string{"generate", "-spec", "medal.yaml", "-radius", "2"}, ioutil.Discard)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "-radius")
+	}
+}
+
+func TestGenerateBuildsEveryEdgeWithDefaultFlags(t *testing.T) {
+	for _, profile := range edgeProfileNames() {
+		// Splines have no points to follow unless they're given some
+		if profile == "spline" {
+			continue
+		}
+		out := filepath.Join(t.TempDir(), "medal.obj")
+		assert.NoError(t, run([]string{"generate", "-edge", profile, "-out", out}, ioutil.Discard), profile)
+		assert.FileExists(t, out, profile)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// engraving is a set of outlines cut into the design face of a medal,
+// leaving a recess with a floor depth below the face.
+type engraving struct {
+	outlines []Outline
+	depth    float64
+}
+
+// medalFace builds the design face of a medal inside the outline at the
+// given height. Faces share the points of the outline so they meet the rim
+// without any gaps.
+type medalFace func(outline []vector.Vector2, height float64) ([]mesh.Polygon, error)
+
+// engravedFace is a flat design face with every engraving cut into it.
+func engravedFace(engravings []engraving) medalFace {
+	return func(outline []vector.Vector2, height float64) ([]mesh.Polygon, error) {
+		return makeFace(outline, height, engravings)
+	}
+}
+
+// makeFace builds the design face of a medal inside the outline at the
+// given height with every engraving cut into it, along with the floor and
+// walls of each recess.
+func makeFace(outline []vector.Vector2, height float64, engravings []engraving) ([]mesh.Polygon, error) {
+	cutouts := make([]Outline, 0)
+	for _, engraving := range engravings {
+		cutouts = append(cutouts, engraving.outlines...)
+	}
+
+	polys, err := carve(outline, cutouts, height)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, engraving := range engravings {
+		recess, err := makeRecess(engraving.outlines, height, engraving.depth)
+		if err != nil {
+			return nil, err
+		}
+		polys = append(polys, recess...)
+	}
+
+	return polys, nil
+}
+
+// makeRecess builds the floor and walls of a hole in the shape of the
+// outlines sunk depth below a face at the given height. The top of the
+// recess is left open for a face carved with the same outlines to close.
+func makeRecess(outlines []Outline, height, depth float64) ([]mesh.Polygon, error) {
+	v, faces, err := triangulateOutlines(outlines)
+	if err != nil {
+		return nil, err
+	}
+	return recessTriangulation(v, faces, height, depth)
+}
+
+func recessTriangulation(v [][2]float64, faces [][3]int32, height, depth float64) ([]mesh.Polygon, error) {
+	bottom := func(i int32) vector.Vector3 { return vector.NewVector3(v[i][0], height-depth, v[i][1]) }
+	top := func(i int32) vector.Vector3 { return vector.NewVector3(v[i][0], height, v[i][1]) }
+
+	oriented := orientUp(v, faces)
+
+	polys := make([]mesh.Polygon, 0, len(oriented))
+	for _, face := range oriented {
+		floorVerts := []vector.Vector3{bottom(face[0]), bottom(face[1]), bottom(face[2])}
+		poly, err := mesh.NewPolygon(floorVerts, floorVerts)
+		if err != nil {
+			return nil, err
+		}
+		polys = append(polys, poly)
+	}
+
+	// Walls face inwards towards the middle of the recess, the opposite of
+	// the walls of an extrusion
+	for _, edge := range capBoundary(oriented) {
+		polys = append(polys, makeSquareWithTexture(
+			bottom(edge[0]),
+			top(edge[0]),
+			top(edge[1]),
+			bottom(edge[1]),
+			vector.NewVector2(0, 0),
+			vector.NewVector2(0, 1),
+			vector.NewVector2(1, 1),
+			vector.NewVector2(1, 0),
+		)...)
+	}
+
+	return polys, nil
+}
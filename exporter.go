@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EliCDavis/mesh"
+)
+
+// medalPart is a single piece of a medal that's saved with its own name and
+// material.
+type medalPart struct {
+	name     string
+	material string
+
+	// materialLibrary is the MTL file the part's material is defined in
+	// when the material came with the part, like the materials of an OBJ
+	// logo. Other parts use the output's library.
+	materialLibrary string
+
+	model mesh.Model
+}
+
+// defaultMaterial is the material of parts that don't have one of their
+// own. OBJ groups carry on with the material of the group before them unless
+// they name another, so every group names one. Viewers draw it the way they
+// draw anything without a material unless the output's library defines it.
+const defaultMaterial = "default"
+
+// writeOBJ writes every part as its own group in Wavefront OBJ format,
+// referencing the material library if one is provided, followed by the
+// libraries the materials of any parts came with.
+func writeOBJ(out io.Writer, parts []medalPart, materialLibrary string) error {
+	if out == nil {
+		return errors.New("Need a writer to write obj to")
+	}
+
+	libraries := []string{materialLibrary}
+	for _, part := range parts {
+		if !containsString(libraries, part.materialLibrary) {
+			libraries = append(libraries, part.materialLibrary)
+		}
+	}
+	for _, library := range libraries {
+		if library == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "mtllib %s\n", library); err != nil {
+			return err
+		}
+	}
+
+	vertexOffset := 1
+	uvOffset := 1
+	for _, part := range parts {
+		if _, err := fmt.Fprintf(out, "g %s\n", part.name); err != nil {
+			return err
+		}
+
+		material := part.material
+		if material == "" {
+			material = defaultMaterial
+		}
+		if _, err := fmt.Fprintf(out, "usemtl %s\n", material); err != nil {
+			return err
+		}
+
+		for _, face := range part.model.GetFaces() {
+			vertices := face.GetVertices()
+			uvs := face.GetUVs()
+			hasUVs := len(uvs) == len(vertices)
+
+			for _, v := range vertices {
+				if _, err := fmt.Fprintf(out, "v %f %f %f\n", v.X(), v.Y(), v.Z()); err != nil {
+					return err
+				}
+			}
+
+			if hasUVs {
+				for _, uv := range uvs {
+					if _, err := fmt.Fprintf(out, "vt %f %f\n", uv.X(), uv.Y()); err != nil {
+						return err
+					}
+				}
+			}
+
+			if _, err := io.WriteString(out, "f"); err != nil {
+				return err
+			}
+			for i := range vertices {
+				var err error
+				if hasUVs {
+					_, err = fmt.Fprintf(out, " %d/%d", vertexOffset+i, uvOffset+i)
+				} else {
+					_, err = fmt.Fprintf(out, " %d", vertexOffset+i)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(out, "\n"); err != nil {
+				return err
+			}
+
+			vertexOffset += len(vertices)
+			if hasUVs {
+				uvOffset += len(uvs)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteOBJKeepsGroupsAndMaterials(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}, {0, 2, 3}}, 1)
+	assert.NoError(t, err)
+
+	// Parts without a material don't carry on with the material of the
+	// part before them
+	parts := []medalPart{
+		{name: "body", material: "wood", model: block},
+		{name: "text_0", model: block},
+		{name: "rim", material: "gold", model: block},
+	}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeOBJ(&out, parts, "master.mtl"))
+
+	obj, err := readOBJ(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"master.mtl"}, obj.MaterialLibraries)
+	if assert.Len(t, obj.Groups, 3) {
+		for i, part := range parts {
+			assert.Equal(t, part.name, obj.Groups[i].Name)
+			assert.Len(t, obj.Groups[i].Faces, len(block.GetFaces()))
+		}
+		assert.Equal(t, "wood", obj.Groups[0].Material)
+		assert.Equal(t, defaultMaterial, obj.Groups[1].Material)
+		assert.Equal(t, "gold", obj.Groups[2].Material)
+	}
+}
+
+func TestWriteOBJReferencesLibrariesPartsCameWith(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}}, 1)
+	assert.NoError(t, err)
+
+	parts := objLibraries([]medalPart{
+		{name: "body", material: "wood", model: block},
+		{name: "logo_0", material: "red", materialLibrary: filepath.Join("logos", "logo.mtl"), model: block},
+		{name: "logo_1", material: "blue", materialLibrary: filepath.Join("logos", "logo.mtl"), model: block},
+	}, "medals")
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeOBJ(&out, parts, "master.mtl"))
+
+	obj, err := readOBJ(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"master.mtl", "../logos/logo.mtl"}, obj.MaterialLibraries)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertClosedManifold checks every edge of the faces is shared by exactly
+// two faces that run along it in opposite directions.
+func assertClosedManifold(t *testing.T, faces []mesh.Polygon) {
+	// Rounded so points that should meet do, where -0 and 0 are the same
+	type edge struct{ a, b [3]float64 }
+	key := func(v vector.Vector3) [3]float64 {
+		round := func(x float64) float64 { return math.Round(x*1e6) / 1e6 }
+		return [3]float64{round(v.X()), round(v.Y()), round(v.Z())}
+	}
+
+	directed := make(map[edge]int)
+	for _, face := range faces {
+		vertices := face.GetVertices()
+		for i := range vertices {
+			directed[edge{key(vertices[i]), key(vertices[(i+1)%len(vertices)])}]++
+		}
+	}
+
+	for e, count := range directed {
+		assert.Equal(t, 1, count, "edge %v used more than once in the same direction", e)
+		assert.Equal(t, 1, directed[edge{e.b, e.a}], "edge %v has no twin", e)
+	}
+}
+
+// signedVolume is positive when every face of a closed model points out.
+func signedVolume(m mesh.Model) float64 {
+	volume := 0.
+	for _, face := range m.GetFaces() {
+		vertices := face.GetVertices()
+		for i := 1; i+1 < len(vertices); i++ {
+			volume += vertices[0].Dot(vertices[i].Cross(vertices[i+1])) / 6.
+		}
+	}
+	return volume
+}
+
+func TestCapBoundary(t *testing.T) {
+	boundary := capBoundary([][3]int32{{0, 1, 2}, {0, 2, 3}})
+	assert.ElementsMatch(t, [][2]int32{{0, 1}, {1, 2}, {2, 3}, {3, 0}}, boundary)
+}
+
+func TestExtrudeTriangulationIsClosedAndFacesOut(t *testing.T) {
+	// A square with a square hole, triangulated by hand and with faces wound
+	// both ways
+	v := [][2]float64{
+		{0, 0}, {3, 0}, {3, 3}, {0, 3},
+		{1, 1}, {2, 1}, {2, 2}, {1, 2},
+	}
+	faces := [][3]int32{
+		{0, 1, 5}, {0, 5, 4},
+		{1, 2, 6}, {6, 5, 1},
+		{2, 3, 7}, {2, 7, 6},
+		{3, 0, 4}, {4, 7, 3},
+	}
+
+	model, err := extrudeTriangulation(v, faces, 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, model.GetFaces(), 16+16)
+	assertClosedManifold(t, model.GetFaces())
+	assert.InDelta(t, 16., signedVolume(model), 1e-9)
+}
+
+func TestFlipWinding(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	model, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}, {0, 2, 3}}, 1)
+	assert.NoError(t, err)
+
+	flipped, err := flipWinding(model)
+	assert.NoError(t, err)
+	assertClosedManifold(t, flipped.GetFaces())
+	assert.InDelta(t, -1., signedVolume(flipped), 1e-9)
+}
+
+func TestRecessClosesCarvedFace(t *testing.T) {
+	// A 3x3 block 2 tall with a 1x1 recess 1 deep cut into the top, carved by
+	// hand the way carve would
+	v := [][2]float64{
+		{0, 0}, {3, 0}, {3, 3}, {0, 3},
+		{1, 1}, {2, 1}, {2, 2}, {1, 2},
+	}
+	carvedFaces := [][3]int32{
+		{0, 1, 5}, {0, 5, 4},
+		{1, 2, 6}, {6, 5, 1},
+		{2, 3, 7}, {2, 7, 6},
+		{3, 0, 4}, {4, 7, 3},
+	}
+
+	block, err := extrudeTriangulation(v[:4], [][3]int32{{0, 1, 2}, {0, 2, 3}}, 2)
+	assert.NoError(t, err)
+
+	polys := make([]mesh.Polygon, 0)
+	for _, face := range block.GetFaces() {
+		onTop := true
+		for _, vert := range face.GetVertices() {
+			onTop = onTop && vert.Y() == 2
+		}
+		if !onTop {
+			polys = append(polys, face)
+		}
+	}
+
+	for _, face := range orientUp(v, carvedFaces) {
+		verts := []vector.Vector3{
+			vector.NewVector3(v[face[0]][0], 2, v[face[0]][1]),
+			vector.NewVector3(v[face[1]][0], 2, v[face[1]][1]),
+			vector.NewVector3(v[face[2]][0], 2, v[face[2]][1]),
+		}
+		poly, err := mesh.NewPolygon(verts, verts)
+		assert.NoError(t, err)
+		polys = append(polys, poly)
+	}
+
+	recess, err := recessTriangulation(
+		[][2]float64{{1, 1}, {2, 1}, {2, 2}, {1, 2}},
+		[][3]int32{{0, 2, 1}, {0, 3, 2}},
+		2,
+		1,
+	)
+	assert.NoError(t, err)
+	assert.Len(t, recess, 2+8)
+	polys = append(polys, recess...)
+
+	model, err := mesh.NewModel(polys)
+	assert.NoError(t, err)
+	assertClosedManifold(t, model.GetFaces())
+	assert.InDelta(t, 17., signedVolume(model), 1e-9)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// splitFontPath separates the index of a font inside a TrueType collection
+// from the path to the collection, written as "fonts/Noto.ttc#2". Paths
+// without an index pick the first font.
+func splitFontPath(path string) (string, int) {
+	hash := strings.LastIndex(path, "#")
+	if hash < 0 {
+		return path, 0
+	}
+
+	index, err := strconv.Atoi(path[hash+1:])
+	if err != nil || index < 0 {
+		return path, 0
+	}
+	return path[:hash], index
+}
+
+// fileExists is whether there's anything at path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// missingFont describes a font that couldn't be found, along with where
+// a relative path was looked for.
+func missingFont(path string) string {
+	if filepath.IsAbs(path) {
+		return fmt.Sprintf("unable to find font %s", path)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Sprintf("unable to find font %s", path)
+	}
+	return fmt.Sprintf("unable to find font %s in %s", path, dir)
+}
+
+// collectionFont returns font data freetype will parse as the font at index
+// in a TrueType collection. freetype only reads the first font of a
+// collection, so the collection's first offset is pointed at the one asked
+// for. Fonts that aren't collections only have a font 0.
+func collectionFont(path string, data []byte, index int) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("font %s is too short to be a TrueType font", path)
+	}
+
+	switch string(data[:4]) {
+	case "OTTO":
+		return nil, fmt.Errorf("font %s has CFF outlines, only TrueType outlines are supported", path)
+	case "ttcf":
+	default:
+		if index != 0 {
+			return nil, fmt.Errorf("font %s isn't a collection, so it has no font %d", path, index)
+		}
+		return data, nil
+	}
+
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font %s is too short to be a TrueType collection", path)
+	}
+	fonts := int(binary.BigEndian.Uint32(data[8:12]))
+	if index >= fonts {
+		return nil, fmt.Errorf("font collection %s has %d fonts, so it has no font %d", path, fonts, index)
+	}
+
+	entry := 12 + (4 * index)
+	if len(data) < entry+4 {
+		return nil, fmt.Errorf("font collection %s is too short to hold font %d", path, index)
+	}
+
+	offset := binary.BigEndian.Uint32(data[entry : entry+4])
+	if int(offset)+4 <= len(data) && string(data[offset:offset+4]) == "OTTO" {
+		return nil, fmt.Errorf("font %d of %s has CFF outlines, only TrueType outlines are supported", index, path)
+	}
+
+	selected := make([]byte, len(data))
+	copy(selected, data)
+	binary.BigEndian.PutUint32(selected[12:16], offset)
+	return selected, nil
+}
+
+// glyphFont is the first of the fonts with a glyph for the character, or
+// the first font when none of them have one.
+func glyphFont(fonts []*truetype.Font, char rune) (*truetype.Font, bool) {
+	for _, parsedFont := range fonts {
+		if parsedFont.Index(char) != 0 {
+			return parsedFont, true
+		}
+	}
+	return fonts[0], false
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeCollection packs fonts into a TrueType collection, moving the offsets
+// of each font's tables to where the font ends up.
+func makeCollection(fonts ...[]byte) []byte {
+	header := 12 + (4 * len(fonts))
+	collection := make([]byte, header)
+	copy(collection, "ttcf")
+	binary.BigEndian.PutUint32(collection[4:], 0x00010000)
+	binary.BigEndian.PutUint32(collection[8:], uint32(len(fonts)))
+
+	for i, data := range fonts {
+		for len(collection)%4 != 0 {
+			collection = append(collection, 0)
+		}
+		base := len(collection)
+		binary.BigEndian.PutUint32(collection[12+(4*i):], uint32(base))
+
+		font := make([]byte, len(data))
+		copy(font, data)
+		tables := int(binary.BigEndian.Uint16(font[4:]))
+		for t := 0; t < tables; t++ {
+			entry := 12 + (16 * t) + 8
+			offset := binary.BigEndian.Uint32(font[entry:])
+			binary.BigEndian.PutUint32(font[entry:], offset+uint32(base))
+		}
+		collection = append(collection, font...)
+	}
+	return collection
+}
+
+func TestSplitFontPath(t *testing.T) {
+	path, index := splitFontPath("fonts/Noto.ttc#2")
+	assert.Equal(t, "fonts/Noto.ttc", path)
+	assert.Equal(t, 2, index)
+
+	path, index = splitFontPath("sample.ttf")
+	assert.Equal(t, "sample.ttf", path)
+	assert.Equal(t, 0, index)
+
+	// Only a number after the last # is an index
+	path, index = splitFontPath("fonts/#1 Font.ttf")
+	assert.Equal(t, "fonts/#1 Font.ttf", path)
+	assert.Equal(t, 0, index)
+}
+
+func TestLoadFontFromCollection(t *testing.T) {
+	sample, err := ioutil.ReadFile("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	tilt, err := ioutil.ReadFile("vtks tilt.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "fonts.ttc")
+	if !assert.NoError(t, ioutil.WriteFile(path, makeCollection(sample, tilt), 0644)) {
+		return
+	}
+
+	name := func(data []byte) string {
+		parsedFont, err := truetype.Parse(data)
+		if err != nil {
+			return ""
+		}
+		return parsedFont.Name(truetype.NameIDFontFullName)
+	}
+
+	first, err := loadFont(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, name(sample), first.Name(truetype.NameIDFontFullName))
+	}
+
+	second, err := loadFont(path + "#1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, name(tilt), second.Name(truetype.NameIDFontFullName))
+	}
+
+	_, err = loadFont(path + "#2")
+	assert.EqualError(t, err, "font collection "+path+"#2 has 2 fonts, so it has no font 2")
+
+	_, err = loadFont("sample.ttf#1")
+	assert.EqualError(t, err, "font sample.ttf#1 isn't a collection, so it has no font 1")
+}
+
+func TestLoadFontProblems(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing.ttf")
+	_, err := loadFont(missing)
+	assert.EqualError(t, err, "unable to find font "+missing)
+
+	_, err = loadFont("missing.ttf")
+	assert.Contains(t, err.Error(), "unable to find font missing.ttf in ")
+
+	cff := filepath.Join(dir, "cff.otf")
+	if assert.NoError(t, ioutil.WriteFile(cff, []byte("OTTO\x00\x0a\x00\x80\x00\x03\x00\x20"), 0644)) {
+		_, err = loadFont(cff)
+		assert.EqualError(t, err, "font "+cff+" has CFF outlines, only TrueType outlines are supported")
+	}
+}
+
+func TestTextToShapeFallsBackOnOtherFonts(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	fallback, err := loadFont("vtks tilt.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// sample.ttf has no space, which the fallback does
+	if !assert.Equal(t, truetype.Index(0), parsedFont.Index(' ')) || !assert.NotEqual(t, truetype.Index(0), fallback.Index(' ')) {
+		return
+	}
+
+	text, err := TextToShape("o o", parsedFont, defaultCurveTolerance, fallback)
+	assert.NoError(t, err)
+	assert.Empty(t, text.Missing)
+	if assert.Len(t, text.Glyphs, 3) {
+		space := fontUnits(fallback.HMetric(glyphLoadScale, fallback.Index(' ')).AdvanceWidth)
+		assert.InDelta(t, space, text.Glyphs[1].Advance, 1e-9)
+	}
+
+	text, err = TextToShape("o o", parsedFont, defaultCurveTolerance)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{' '}, text.Missing)
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// glTF constants used throughout the document
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+	gltfLinear       = 9729
+	gltfLinearMipmap = 9987
+	gltfRepeat       = 10497
+)
+
+// metersPerUnit converts the units a medal is modelled in to glTF's meters.
+var metersPerUnit = map[string]float64{
+	"mm": .001,
+	"cm": .01,
+	"m":  1,
+	"in": .0254,
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name     string    `json:"name,omitempty"`
+	Mesh     *int      `json:"mesh,omitempty"`
+	Children []int     `json:"children,omitempty"`
+	Scale    []float64 `json:"scale,omitempty"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   *int           `json:"material,omitempty"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfTextureInfo struct {
+	Index int `json:"index"`
+}
+
+// gltfPBR leaves nothing out, as glTF treats missing metallic and roughness
+// factors as 1.
+type gltfPBR struct {
+	BaseColorFactor  [4]float64       `json:"baseColorFactor"`
+	BaseColorTexture *gltfTextureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   float64          `json:"metallicFactor"`
+	RoughnessFactor  float64          `json:"roughnessFactor"`
+}
+
+type gltfMaterial struct {
+	Name                 string  `json:"name,omitempty"`
+	PbrMetallicRoughness gltfPBR `json:"pbrMetallicRoughness"`
+	AlphaMode            string  `json:"alphaMode,omitempty"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfImage struct {
+	Name       string `json:"name,omitempty"`
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri,omitempty"`
+}
+
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Textures    []gltfTexture    `json:"textures,omitempty"`
+	Images      []gltfImage      `json:"images,omitempty"`
+	Samplers    []gltfSampler    `json:"samplers,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+}
+
+// gltfBuilder builds up a glTF document along with the single binary
+// buffer every mesh and image is stored in.
+type gltfBuilder struct {
+	doc  gltfDocument
+	data bytes.Buffer
+
+	library         map[string]*mtlMaterial
+	partLibraries   map[string]map[string]*mtlMaterial
+	materialIndices map[gltfMaterialKey]int
+	textureIndices  map[string]int
+}
+
+// gltfMaterialKey is a material by its name and the library it's from,
+// where an empty library is the output's.
+type gltfMaterialKey struct {
+	library string
+	name    string
+}
+
+// addBufferView appends the data to the buffer, keeping everything aligned
+// to 4 bytes.
+func (b *gltfBuilder) addBufferView(data []byte, target int) int {
+	view := gltfBufferView{ByteOffset: b.data.Len(), ByteLength: len(data), Target: target}
+	b.data.Write(data)
+	for b.data.Len()%4 != 0 {
+		b.data.WriteByte(0)
+	}
+	b.doc.BufferViews = append(b.doc.BufferViews, view)
+	return len(b.doc.BufferViews) - 1
+}
+
+func (b *gltfBuilder) addFloatAccessor(values [][]float32, accessorType string, withBounds bool) int {
+	components := len(values[0])
+	data := make([]byte, 0, len(values)*components*4)
+	min := make([]float64, components)
+	max := make([]float64, components)
+	for i := range min {
+		min[i] = math.Inf(1)
+		max[i] = math.Inf(-1)
+	}
+
+	for _, value := range values {
+		for i, component := range value {
+			data = appendUint32(data, math.Float32bits(component))
+			min[i] = math.Min(min[i], float64(component))
+			max[i] = math.Max(max[i], float64(component))
+		}
+	}
+
+	accessor := gltfAccessor{
+		BufferView:    b.addBufferView(data, gltfArrayBuffer),
+		ComponentType: gltfFloat,
+		Count:         len(values),
+		Type:          accessorType,
+	}
+	if withBounds {
+		accessor.Min = min
+		accessor.Max = max
+	}
+	b.doc.Accessors = append(b.doc.Accessors, accessor)
+	return len(b.doc.Accessors) - 1
+}
+
+func (b *gltfBuilder) addIndexAccessor(indices []uint32) int {
+	data := make([]byte, 0, len(indices)*4)
+	for _, index := range indices {
+		data = appendUint32(data, index)
+	}
+	b.doc.Accessors = append(b.doc.Accessors, gltfAccessor{
+		BufferView:    b.addBufferView(data, gltfElementArray),
+		ComponentType: gltfUnsignedInt,
+		Count:         len(indices),
+		Type:          "SCALAR",
+	})
+	return len(b.doc.Accessors) - 1
+}
+
+// addTexture embeds the image at path, only embedding each image once.
+func (b *gltfBuilder) addTexture(path string) (int, error) {
+	if index, ok := b.textureIndices[path]; ok {
+		return index, nil
+	}
+
+	var mimeType string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		mimeType = "image/jpeg"
+	case ".png":
+		mimeType = "image/png"
+	default:
+		return 0, fmt.Errorf("unsupported texture %s, must be a JPEG or PNG", path)
+	}
+
+	image, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(b.doc.Samplers) == 0 {
+		b.doc.Samplers = append(b.doc.Samplers, gltfSampler{
+			MagFilter: gltfLinear,
+			MinFilter: gltfLinearMipmap,
+			WrapS:     gltfRepeat,
+			WrapT:     gltfRepeat,
+		})
+	}
+
+	b.doc.Images = append(b.doc.Images, gltfImage{
+		Name:       filepath.Base(path),
+		BufferView: b.addBufferView(image, 0),
+		MimeType:   mimeType,
+	})
+	b.doc.Textures = append(b.doc.Textures, gltfTexture{Sampler: 0, Source: len(b.doc.Images) - 1})
+
+	b.textureIndices[path] = len(b.doc.Textures) - 1
+	return b.textureIndices[path], nil
+}
+
+// pbrFromMTL approximates a Phong MTL material with glTF's metallic
+// roughness model. The brightest specular channel stands in for how
+// metallic the material is and the specular exponent is turned into a
+// roughness. Textured materials use the texture's colors untinted.
+func pbrFromMTL(material *mtlMaterial) gltfMaterial {
+	pbr := gltfPBR{
+		BaseColorFactor: [4]float64{material.Diffuse[0], material.Diffuse[1], material.Diffuse[2], material.Dissolve},
+		MetallicFactor:  math.Max(material.Specular[0], math.Max(material.Specular[1], material.Specular[2])),
+		RoughnessFactor: math.Sqrt(2. / (material.Shininess + 2.)),
+	}
+	if material.DiffuseTexture != "" {
+		pbr.BaseColorFactor = [4]float64{1, 1, 1, material.Dissolve}
+	}
+
+	converted := gltfMaterial{Name: material.Name, PbrMetallicRoughness: pbr}
+	if material.Dissolve < 1 {
+		converted.AlphaMode = "BLEND"
+	}
+	return converted
+}
+
+// addMaterial converts the named material from the library, only
+// converting each material once. Materials that came with a part are read
+// from the part's own library. Others are required to be in the output's
+// library when there is one, and are left as plain white otherwise.
+func (b *gltfBuilder) addMaterial(name, library string) (int, error) {
+	key := gltfMaterialKey{library: library, name: name}
+	if index, ok := b.materialIndices[key]; ok {
+		return index, nil
+	}
+
+	material := &mtlMaterial{Name: name, Diffuse: [3]float64{1, 1, 1}, Dissolve: 1}
+	switch {
+	case library != "":
+		materials, ok := b.partLibraries[library]
+		if !ok {
+			var err error
+			if materials, err = loadMTL(library); err != nil {
+				return 0, err
+			}
+			b.partLibraries[library] = materials
+		}
+		found, ok := materials[name]
+		if !ok {
+			return 0, fmt.Errorf("material %q isn't in %s", name, library)
+		}
+		material = found
+	case b.library != nil:
+		found, ok := b.library[name]
+		if !ok {
+			return 0, fmt.Errorf("material %q isn't in the material library", name)
+		}
+		material = found
+	}
+
+	converted := pbrFromMTL(material)
+	if material.DiffuseTexture != "" {
+		texture, err := b.addTexture(material.DiffuseTexture)
+		if err != nil {
+			return 0, err
+		}
+		converted.PbrMetallicRoughness.BaseColorTexture = &gltfTextureInfo{Index: texture}
+	}
+
+	b.doc.Materials = append(b.doc.Materials, converted)
+	b.materialIndices[key] = len(b.doc.Materials) - 1
+	return b.materialIndices[key], nil
+}
+
+// addPart turns the part into an indexed mesh with flat normals calculated
+// from each face's winding. Returns the index of the part's node, or -1 if
+// the part has nothing in it.
+func (b *gltfBuilder) addPart(part medalPart) (int, error) {
+	type gltfVertex struct {
+		position [3]float32
+		normal   [3]float32
+		uv       [2]float32
+	}
+
+	vertexIndices := make(map[gltfVertex]uint32)
+	vertices := make([]gltfVertex, 0)
+	indices := make([]uint32, 0)
+	hasUVs := false
+
+	for _, face := range part.model.GetFaces() {
+		positions := face.GetVertices()
+		uvs := face.GetUVs()
+		faceHasUVs := len(uvs) == len(positions)
+		hasUVs = hasUVs || faceHasUVs
+
+		for i := 1; i+1 < len(positions); i++ {
+			corners := []int{0, i, i + 1}
+
+			normal := positions[i].Sub(positions[0]).Cross(positions[i+1].Sub(positions[0]))
+			if normal.Length() > 0 {
+				normal = normal.Normalized()
+			}
+
+			for _, corner := range corners {
+				p := positions[corner]
+				vertex := gltfVertex{
+					position: [3]float32{float32(p.X()), float32(p.Y()), float32(p.Z())},
+					normal:   [3]float32{float32(normal.X()), float32(normal.Y()), float32(normal.Z())},
+				}
+				if faceHasUVs {
+					// glTF's textures start in the top left rather than the
+					// bottom left like OBJ
+					uv := uvs[corner]
+					vertex.uv = [2]float32{float32(uv.X()), float32(1 - uv.Y())}
+				}
+
+				index, ok := vertexIndices[vertex]
+				if !ok {
+					index = uint32(len(vertices))
+					vertexIndices[vertex] = index
+					vertices = append(vertices, vertex)
+				}
+				indices = append(indices, index)
+			}
+		}
+	}
+
+	if len(indices) == 0 {
+		return -1, nil
+	}
+
+	positions := make([][]float32, len(vertices))
+	normals := make([][]float32, len(vertices))
+	uvs := make([][]float32, len(vertices))
+	for i, vertex := range vertices {
+		positions[i] = vertex.position[:]
+		normals[i] = vertex.normal[:]
+		uvs[i] = vertex.uv[:]
+	}
+
+	primitive := gltfPrimitive{
+		Attributes: map[string]int{
+			"POSITION": b.addFloatAccessor(positions, "VEC3", true),
+			"NORMAL":   b.addFloatAccessor(normals, "VEC3", false),
+		},
+		Indices: b.addIndexAccessor(indices),
+	}
+	if hasUVs {
+		primitive.Attributes["TEXCOORD_0"] = b.addFloatAccessor(uvs, "VEC2", false)
+	}
+
+	// Parts without a material get the library's default material when it
+	// defines one, the same as they would in an OBJ viewer
+	name := part.material
+	if _, ok := b.library[defaultMaterial]; name == "" && ok {
+		name = defaultMaterial
+	}
+	if name != "" {
+		material, err := b.addMaterial(name, part.materialLibrary)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", part.name, err)
+		}
+		primitive.Material = &material
+	}
+
+	b.doc.Meshes = append(b.doc.Meshes, gltfMesh{Name: part.name, Primitives: []gltfPrimitive{primitive}})
+	meshIndex := len(b.doc.Meshes) - 1
+	b.doc.Nodes = append(b.doc.Nodes, gltfNode{Name: part.name, Mesh: &meshIndex})
+	return len(b.doc.Nodes) - 1, nil
+}
+
+// buildGLTF turns every part into its own node under a single medal node
+// that's scaled from the medal's units into meters.
+func buildGLTF(parts []medalPart, library map[string]*mtlMaterial, units string) (*gltfBuilder, error) {
+	scale, ok := metersPerUnit[units]
+	if !ok {
+		return nil, fmt.Errorf("unsupported units %q", units)
+	}
+
+	b := &gltfBuilder{
+		doc: gltfDocument{
+			Asset: gltfAsset{Version: "2.0", Generator: "medal-generation"},
+		},
+		library:         library,
+		partLibraries:   make(map[string]map[string]*mtlMaterial),
+		materialIndices: make(map[gltfMaterialKey]int),
+		textureIndices:  make(map[string]int),
+	}
+
+	children := make([]int, 0, len(parts))
+	for _, part := range parts {
+		node, err := b.addPart(part)
+		if err != nil {
+			return nil, err
+		}
+		if node != -1 {
+			children = append(children, node)
+		}
+	}
+
+	if len(children) == 0 {
+		return nil, errors.New("Need at least one face to write a gltf")
+	}
+
+	b.doc.Nodes = append(b.doc.Nodes, gltfNode{Name: "medal", Children: children, Scale: []float64{scale, scale, scale}})
+	b.doc.Scenes = []gltfScene{{Nodes: []int{len(b.doc.Nodes) - 1}}}
+	return b, nil
+}
+
+// writeGLTF writes every part as a glTF JSON document with the buffer and
+// any textures embedded as a base64 data URI.
+func writeGLTF(out io.Writer, parts []medalPart, library map[string]*mtlMaterial, units string) error {
+	if out == nil {
+		return errors.New("Need a writer to write gltf to")
+	}
+
+	b, err := buildGLTF(parts, library, units)
+	if err != nil {
+		return err
+	}
+
+	b.doc.Buffers = []gltfBuffer{{
+		ByteLength: b.data.Len(),
+		URI:        "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b.data.Bytes()),
+	}}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b.doc)
+}
+
+// writeGLB writes every part as a single binary glTF file with the buffer
+// and any textures embedded in it.
+func writeGLB(out io.Writer, parts []medalPart, library map[string]*mtlMaterial, units string) error {
+	if out == nil {
+		return errors.New("Need a writer to write glb to")
+	}
+
+	b, err := buildGLTF(parts, library, units)
+	if err != nil {
+		return err
+	}
+	b.doc.Buffers = []gltfBuffer{{ByteLength: b.data.Len()}}
+
+	document, err := json.Marshal(b.doc)
+	if err != nil {
+		return err
+	}
+	for len(document)%4 != 0 {
+		document = append(document, ' ')
+	}
+
+	// The buffer is already padded to 4 bytes as views are added
+	bin := b.data.Bytes()
+
+	header := make([]byte, 0, 28)
+	header = append(header, "glTF"...)
+	header = appendUint32(header, 2)
+	header = appendUint32(header, uint32(12+8+len(document)+8+len(bin)))
+	header = appendUint32(header, uint32(len(document)))
+	header = append(header, "JSON"...)
+
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+	if _, err := out.Write(document); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, 8)
+	chunk = appendUint32(chunk, uint32(len(bin)))
+	chunk = append(chunk, 'B', 'I', 'N', 0)
+	if _, err := out.Write(chunk); err != nil {
+		return err
+	}
+	_, err = out.Write(bin)
+	return err
+}
+
+func appendUint32(data []byte, value uint32) []byte {
+	encoded := make([]byte, 4)
+	binary.LittleEndian.PutUint32(encoded, value)
+	return append(data, encoded...)
+}
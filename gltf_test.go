@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readGLB splits a GLB file back into its document and binary buffer.
+func readGLB(t *testing.T, data []byte) (gltfDocument, []byte) {
+	var doc gltfDocument
+	if !assert.GreaterOrEqual(t, len(data), 20) {
+		return doc, nil
+	}
+
+	assert.Equal(t, "glTF", string(data[:4]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data[4:]))
+	assert.Equal(t, uint32(len(data)), binary.LittleEndian.Uint32(data[8:]))
+
+	jsonLength := binary.LittleEndian.Uint32(data[12:])
+	assert.Equal(t, "JSON", string(data[16:20]))
+	assert.Zero(t, jsonLength%4)
+	assert.NoError(t, json.Unmarshal(data[20:20+jsonLength], &doc))
+
+	bin := data[20+jsonLength:]
+	binLength := binary.LittleEndian.Uint32(bin)
+	assert.Equal(t, []byte{'B', 'I', 'N', 0}, bin[4:8])
+	return doc, bin[8 : 8+binLength]
+}
+
+func TestWriteGLBEmbedsMaterialsAndTextures(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}, {0, 2, 3}}, 1)
+	assert.NoError(t, err)
+
+	library, err := loadMTL("master.mtl")
+	assert.NoError(t, err)
+
+	parts := []medalPart{
+		{name: "body", material: "wood", model: block},
+		{name: "text", material: "gold", model: block},
+		{name: "logo", material: "wood", model: block},
+	}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeGLB(&out, parts, library, "mm"))
+	doc, bin := readGLB(t, out.Bytes())
+
+	assert.Equal(t, "2.0", doc.Asset.Version)
+	assert.Len(t, doc.Meshes, 3)
+	assert.Len(t, doc.Materials, 2)
+	if assert.Len(t, doc.Images, 1) {
+		jpg, err := ioutil.ReadFile("wood.jpg")
+		assert.NoError(t, err)
+
+		view := doc.BufferViews[doc.Images[0].BufferView]
+		assert.Equal(t, "image/jpeg", doc.Images[0].MimeType)
+		assert.Equal(t, jpg, bin[view.ByteOffset:view.ByteOffset+view.ByteLength])
+	}
+
+	wood := doc.Materials[0].PbrMetallicRoughness
+	assert.Equal(t, "wood", doc.Materials[0].Name)
+	if assert.NotNil(t, wood.BaseColorTexture) {
+		assert.Equal(t, 0, wood.BaseColorTexture.Index)
+	}
+
+	gold := doc.Materials[1].PbrMetallicRoughness
+	assert.Equal(t, "gold", doc.Materials[1].Name)
+	assert.Equal(t, 1., gold.MetallicFactor)
+	assert.Less(t, gold.RoughnessFactor, .1)
+
+	medal := doc.Nodes[doc.Scenes[doc.Scene].Nodes[0]]
+	assert.Equal(t, []float64{.001, .001, .001}, medal.Scale)
+	assert.Len(t, medal.Children, 3)
+
+	// Every side of the block has its own 4 corners once normals are flat
+	body := doc.Meshes[0].Primitives[0]
+	assert.Equal(t, 24, doc.Accessors[body.Attributes["POSITION"]].Count)
+	assert.Equal(t, 36, doc.Accessors[body.Indices].Count)
+	assert.Contains(t, body.Attributes, "TEXCOORD_0")
+}
+
+func TestWriteGLTFRequiresMaterialsInLibrary(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}}, 1)
+	assert.NoError(t, err)
+
+	err = writeGLTF(ioutil.Discard, []medalPart{{name: "body", material: "marble", model: block}}, map[string]*mtlMaterial{}, "mm")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "marble")
+	}
+}
+
+func TestWriteGLTFUsesLogoMaterials(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "logo.obj"), []byte("mtllib logo.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl red\nf 1 2 3\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "logo.mtl"), []byte("newmtl red\nKd 1 0 0\n"), 0644))
+
+	parts, err := loadLogo(filepath.Join(dir, "logo.obj"))
+	if !assert.NoError(t, err) || !assert.Len(t, parts, 1) {
+		return
+	}
+	assert.Equal(t, "red", parts[0].material)
+	assert.Equal(t, filepath.Join(dir, "logo.mtl"), parts[0].materialLibrary)
+
+	// The output's library doesn't have the logo's material
+	library, err := loadMTL("master.mtl")
+	assert.NoError(t, err)
+
+	out := bytes.Buffer{}
+	if !assert.NoError(t, writeGLTF(&out, parts, library, "mm")) {
+		return
+	}
+
+	var doc gltfDocument
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	if assert.Len(t, doc.Materials, 1) {
+		assert.Equal(t, "red", doc.Materials[0].Name)
+		assert.Equal(t, [4]float64{1, 0, 0, 1}, doc.Materials[0].PbrMetallicRoughness.BaseColorFactor)
+	}
+}
+
+func TestWriteGLTFUsesTheLibrarysDefaultMaterial(t *testing.T) {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}}, 1)
+	assert.NoError(t, err)
+	parts := []medalPart{{name: "text_0", model: block}}
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeGLTF(&out, parts, map[string]*mtlMaterial{}, "mm"))
+	var doc gltfDocument
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Empty(t, doc.Materials)
+
+	library := map[string]*mtlMaterial{
+		defaultMaterial: {Name: defaultMaterial, Diffuse: [3]float64{0, 0, 1}, Dissolve: 1},
+	}
+	out.Reset()
+	assert.NoError(t, writeGLTF(&out, parts, library, "mm"))
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	if assert.Len(t, doc.Materials, 1) {
+		assert.Equal(t, defaultMaterial, doc.Materials[0].Name)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// glyphScale is how much the points of a glyph are shrunk down by after
+// being loaded from a font.
+const glyphScale = .01
+
+// glyphLoadScale is the size glyphs and their metrics are loaded from a
+// font at, which glyphScale brings back down to one unit per em.
+const glyphLoadScale fixed.Int26_6 = 100
+
+// fontUnits converts a measurement taken from a font at glyphLoadScale into
+// the units of the text.
+func fontUnits(value fixed.Int26_6) float64 {
+	return float64(value) * glyphScale
+}
+
+// defaultCurveTolerance is the furthest a flattened glyph curve is allowed
+// to stray from the real curve, in the same units as the text before it's
+// scaled.
+const defaultCurveTolerance = .01
+
+func glyphPoint(p truetype.Point) vector.Vector2 {
+	return vector.NewVector2(float64(p.X), float64(p.Y)).MultByConstant(glyphScale)
+}
+
+func glyphPointOnCurve(p truetype.Point) bool {
+	return p.Flags&1 == 1
+}
+
+// flattenQuadratic approximates the quadratic bézier curve from start to end
+// with line segments that stray no further than tolerance from the curve.
+// The points returned exclude start and include end.
+func flattenQuadratic(start, control, end vector.Vector2, tolerance float64) []vector.Vector2 {
+	// The furthest a chord strays from a quadratic curve split into n even
+	// pieces is |start - 2*control + end| / (4 * n^2)
+	deviation := start.Sub(control.MultByConstant(2)).Add(end).Length()
+	segments := 1
+	if tolerance > 0 && deviation > 0 {
+		segments = int(math.Ceil(math.Sqrt(deviation / (4 * tolerance))))
+	}
+
+	points := make([]vector.Vector2, segments)
+	for i := 1; i <= segments; i++ {
+		t := float64(i) / float64(segments)
+		a := start.MultByConstant((1 - t) * (1 - t))
+		b := control.MultByConstant(2 * (1 - t) * t)
+		c := end.MultByConstant(t * t)
+		points[i-1] = a.Add(b).Add(c)
+	}
+	return points
+}
+
+// flattenContour turns a single TrueType contour of on and off curve points
+// into a polygon. Consecutive off curve points have an implied on curve
+// point halfway between them.
+func flattenContour(contour []truetype.Point, tolerance float64) []vector.Vector2 {
+	if len(contour) == 0 {
+		return nil
+	}
+
+	// Find somewhere on the curve to start from, falling back to the implied
+	// point between the first two when every point is off the curve.
+	startIndex := -1
+	for i, p := range contour {
+		if glyphPointOnCurve(p) {
+			startIndex = i
+			break
+		}
+	}
+
+	var start vector.Vector2
+	if startIndex == -1 {
+		startIndex = 0
+		start = glyphPoint(contour[0]).Add(glyphPoint(contour[1%len(contour)])).MultByConstant(.5)
+	} else {
+		start = glyphPoint(contour[startIndex])
+	}
+
+	points := []vector.Vector2{start}
+	current := start
+
+	var control *vector.Vector2
+	for offset := 1; offset <= len(contour); offset++ {
+		p := contour[(startIndex+offset)%len(contour)]
+		pos := glyphPoint(p)
+
+		if glyphPointOnCurve(p) {
+			if control == nil {
+				points = append(points, pos)
+			} else {
+				points = append(points, flattenQuadratic(current, *control, pos, tolerance)...)
+				control = nil
+			}
+			current = pos
+			continue
+		}
+
+		if control != nil {
+			implied := control.Add(pos).MultByConstant(.5)
+			points = append(points, flattenQuadratic(current, *control, implied, tolerance)...)
+			current = implied
+		}
+		c := pos
+		control = &c
+	}
+
+	// The contour closes back on the starting point
+	if control != nil {
+		points = append(points, flattenQuadratic(current, *control, start, tolerance)...)
+	}
+
+	// The last point is where we started
+	points = points[:len(points)-1]
+
+	return dedupePoints(points)
+}
+
+// dedupePoints removes consecutive points that sit on top of one another,
+// including the last and first point.
+func dedupePoints(points []vector.Vector2) []vector.Vector2 {
+	const epsilon = 1e-9
+
+	deduped := make([]vector.Vector2, 0, len(points))
+	for _, p := range points {
+		if len(deduped) > 0 && deduped[len(deduped)-1].Distance(p) < epsilon {
+			continue
+		}
+		deduped = append(deduped, p)
+	}
+
+	for len(deduped) > 1 && deduped[0].Distance(deduped[len(deduped)-1]) < epsilon {
+		deduped = deduped[:len(deduped)-1]
+	}
+	return deduped
+}
+
+// glyphContours splits a loaded glyph into each of its closed contours,
+// flattening any curves along the way. Contours come back in the same
+// winding as the font defines them, which classifyContours uses to tell
+// shells apart from holes.
+func glyphContours(glyph *truetype.GlyphBuf, tolerance float64) [][]vector.Vector2 {
+	contours := make([][]vector.Vector2, 0, len(glyph.Ends))
+
+	contourStart := 0
+	for _, contourEnd := range glyph.Ends {
+		points := flattenContour(glyph.Points[contourStart:contourEnd], tolerance)
+		contourStart = contourEnd
+
+		if len(points) < 3 {
+			continue
+		}
+		contours = append(contours, points)
+	}
+
+	return contours
+}
+
+// glyphShapes loads the contours of the glyph for char with the glyph's
+// origin at (0, 0). Glyphs without any contours, like spaces, have no
+// shapes.
+func glyphShapes(parsedFont *truetype.Font, char rune, tolerance float64) ([]mesh.Shape, error) {
+	glyph := truetype.GlyphBuf{}
+	if err := glyph.Load(parsedFont, glyphLoadScale, parsedFont.Index(char), font.HintingNone); err != nil {
+		return nil, err
+	}
+
+	contours := make([]mesh.Shape, 0, len(glyph.Ends))
+	for _, contourPoints := range glyphContours(&glyph, tolerance) {
+		contour, err := mesh.NewShape(contourPoints)
+		if err != nil {
+			return nil, err
+		}
+		contours = append(contours, contour)
+	}
+	return contours, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/golang/freetype/truetype"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func onCurve(x, y int) truetype.Point {
+	return truetype.Point{X: fixed.Int26_6(x), Y: fixed.Int26_6(y), Flags: 1}
+}
+
+func offCurve(x, y int) truetype.Point {
+	return truetype.Point{X: fixed.Int26_6(x), Y: fixed.Int26_6(y)}
+}
+
+func TestFlattenQuadraticStaysWithinTolerance(t *testing.T) {
+	start := vector.NewVector2(0, 0)
+	control := vector.NewVector2(1, 2)
+	end := vector.NewVector2(2, 0)
+	tolerance := 0.001
+
+	points := append([]vector.Vector2{start}, flattenQuadratic(start, control, end, tolerance)...)
+	assert.Equal(t, end, points[len(points)-1])
+
+	// Chords stray the most halfway between their ends
+	segments := float64(len(points) - 1)
+	for i := 0; i < len(points)-1; i++ {
+		t0 := (float64(i) + .5) / segments
+		curve := start.MultByConstant((1 - t0) * (1 - t0)).
+			Add(control.MultByConstant(2 * (1 - t0) * t0)).
+			Add(end.MultByConstant(t0 * t0))
+		chord := points[i].Add(points[i+1]).MultByConstant(.5)
+		assert.LessOrEqual(t, curve.Distance(chord), tolerance)
+	}
+}
+
+func TestFlattenContourOnlyOnCurvePoints(t *testing.T) {
+	points := flattenContour([]truetype.Point{
+		onCurve(0, 0),
+		onCurve(100, 0),
+		onCurve(100, 100),
+		onCurve(0, 100),
+	}, defaultCurveTolerance)
+
+	assert.Equal(t, []vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(1, 0),
+		vector.NewVector2(1, 1),
+		vector.NewVector2(0, 1),
+	}, points)
+}
+
+func TestFlattenContourImpliesPointsBetweenOffCurvePoints(t *testing.T) {
+	// A rounded diamond made entirely out of control points
+	points := flattenContour([]truetype.Point{
+		offCurve(100, 0),
+		offCurve(0, 100),
+		offCurve(-100, 0),
+		offCurve(0, -100),
+	}, 0.0001)
+
+	assert.Greater(t, len(points), 8)
+	for _, p := range points {
+		// Every point lies on the curves between the implied midpoints, which
+		// stay between the inner and outer diamond.
+		assert.LessOrEqual(t, math.Abs(p.X())+math.Abs(p.Y()), 1.0+1e-9)
+		assert.GreaterOrEqual(t, math.Abs(p.X())+math.Abs(p.Y()), 0.5-1e-9)
+	}
+}
+
+func TestGlyphContoursFindsCounters(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	glyph := truetype.GlyphBuf{}
+	assert.NoError(t, glyph.Load(parsedFont, 100, parsedFont.Index('o'), font.HintingNone))
+
+	contours := glyphContours(&glyph, defaultCurveTolerance)
+	assert.Len(t, contours, 2)
+
+	shapes := make([]mesh.Shape, len(contours))
+	for i, contour := range contours {
+		shapes[i], err = mesh.NewShape(contour)
+		assert.NoError(t, err)
+	}
+
+	outlines := classifyContours(shapes)
+	if assert.Len(t, outlines, 1) {
+		assert.Len(t, outlines[0].Holes, 1)
+	}
+}
+
+func TestTextToShapeUsesAdvanceWidths(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	text, err := TextToShape("o o", parsedFont, defaultCurveTolerance)
+	assert.NoError(t, err)
+	if !assert.Len(t, text.Glyphs, 3) {
+		return
+	}
+
+	// Spaces keep their place in the text without any shapes
+	assert.Empty(t, text.Glyphs[1].Shapes)
+	assert.Equal(t, " ", text.Glyphs[1].Text)
+
+	advance := func(r rune) float64 {
+		return fontUnits(parsedFont.HMetric(glyphLoadScale, parsedFont.Index(r)).AdvanceWidth)
+	}
+	kern := func(a, b rune) float64 {
+		return fontUnits(parsedFont.Kern(glyphLoadScale, parsedFont.Index(a), parsedFont.Index(b)))
+	}
+
+	assert.Equal(t, 0., text.Glyphs[0].Origin.X())
+	assert.InDelta(t, advance('o')+kern('o', ' '), text.Glyphs[1].Origin.X(), 1e-9)
+	assert.InDelta(t, advance('o')+kern('o', ' ')+advance(' ')+kern(' ', 'o'), text.Glyphs[2].Origin.X(), 1e-9)
+	assert.InDelta(t, text.Glyphs[2].Origin.X()+advance('o'), text.Width, 1e-9)
+
+	// Both o's are the same shape, just moved along by their origins
+	first := text.Glyphs[0].Shapes[0].GetPoints()
+	last := text.Glyphs[2].Shapes[0].GetPoints()
+	if assert.Equal(t, len(first), len(last)) {
+		for i := range first {
+			assert.InDelta(t, first[i].X()+text.Glyphs[2].Origin.X(), last[i].X(), 1e-9)
+			assert.InDelta(t, first[i].Y(), last[i].Y(), 1e-9)
+		}
+	}
+}
+
+func TestTextToShapeCountsRunesNotBytes(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	text, err := TextToShape("éo", parsedFont, defaultCurveTolerance)
+	assert.NoError(t, err)
+	if assert.Len(t, text.Glyphs, 2) {
+		assert.Equal(t, "é", text.Glyphs[0].Text)
+		assert.Equal(t, "o", text.Glyphs[1].Text)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 
@@ -12,128 +13,434 @@ import (
 	"github.com/EliCDavis/vector"
 )
 
-func firstWords(value string, count int) (string, string) {
-	// Loop over all indexes in the string.
-	for i := range value {
-		// If we encounter a space, reduce the count.
-		if value[i] == ' ' {
-			count--
-			// When no more words required, return a substring.
-			if count == 0 {
-				return value[0:i], value[i+1:]
-			}
+// objGroup is every face read from an OBJ file under the same object and
+// group.
+type objGroup struct {
+	// Object is the name given by the last o statement
+	Object string
+
+	// Name is the name given by the last g statement
+	Name string
+
+	// Material is the name given by the last usemtl statement
+	Material string
+
+	Faces []mesh.Polygon
+}
+
+// objFile is everything read from an OBJ file.
+type objFile struct {
+	// MaterialLibraries are the MTL files named by mtllib statements, as
+	// they're written in the file
+	MaterialLibraries []string
+
+	Groups []objGroup
+}
+
+// objCorner is a single corner of a face, with indices into the vertices,
+// texture coordinates and normals read so far. Missing indices are -1.
+type objCorner struct {
+	vertex  int
+	texture int
+	normal  int
+}
+
+// parseOBJFloats parses between min and max numbers, padding out to max
+// with the fallback.
+func parseOBJFloats(fields []string, min, max int, fallback float64) ([]float64, error) {
+	if len(fields) < min || len(fields) > max {
+		if min == max {
+			return nil, fmt.Errorf("expected %d values, got %d", min, len(fields))
+		}
+		return nil, fmt.Errorf("expected %d to %d values, got %d", min, max, len(fields))
+	}
+
+	values := make([]float64, max)
+	for i := range values {
+		if i >= len(fields) {
+			values[i] = fallback
+			continue
 		}
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %q as a number", fields[i])
+		}
+		values[i] = value
 	}
-	// Return the entire string.
-	return value, ""
+	return values, nil
 }
 
-func strToVector(str string) (*vector.Vector3, error) {
-	components := strings.Split(str, " ")
+// resolveOBJIndex turns a 1 based index, or a negative index counting back
+// from the most recent element, into a 0 based index into count elements.
+func resolveOBJIndex(field string, count int, kind string) (int, error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		return -1, fmt.Errorf("unable to parse %s index %q", kind, field)
+	}
 
-	if len(components) != 3 {
-		return nil, errors.New("unable to parse: " + str)
+	resolved := index - 1
+	if index < 0 {
+		resolved = count + index
 	}
 
-	xParse, err := strconv.ParseFloat(components[0], 64)
-	if err != nil {
-		return nil, errors.New("unable to parse X componenent: " + components[0])
+	if index == 0 || resolved < 0 || resolved >= count {
+		return -1, fmt.Errorf("%s index %d is out of range, only %d have been defined", kind, index, count)
 	}
+	return resolved, nil
+}
 
-	yParse, err := strconv.ParseFloat(components[1], 64)
-	if err != nil {
-		return nil, errors.New("unable to parse Y componenent: " + components[1])
+// parseFaceCorner parses a corner of a face written as v, v/vt, v//vn or
+// v/vt/vn.
+func parseFaceCorner(field string, vertices, textures, normals int) (objCorner, error) {
+	corner := objCorner{vertex: -1, texture: -1, normal: -1}
+
+	parts := strings.Split(field, "/")
+	if len(parts) > 3 {
+		return corner, fmt.Errorf("unable to parse face corner %q", field)
 	}
 
-	zParse, err := strconv.ParseFloat(components[2], 64)
+	var err error
+	corner.vertex, err = resolveOBJIndex(parts[0], vertices, "vertex")
 	if err != nil {
-		return nil, errors.New("unable to parse Z componenent: " + components[2])
+		return corner, err
 	}
 
-	v := vector.NewVector3(xParse, yParse, zParse)
-	return &v, nil
+	if len(parts) > 1 && parts[1] != "" {
+		corner.texture, err = resolveOBJIndex(parts[1], textures, "texture coordinate")
+		if err != nil {
+			return corner, err
+		}
+	}
+
+	if len(parts) > 2 && parts[2] != "" {
+		corner.normal, err = resolveOBJIndex(parts[2], normals, "normal")
+		if err != nil {
+			return corner, err
+		}
+	}
+
+	return corner, nil
 }
 
-func strToFaceIndexes(str string) (int, int, int, error) {
-	// 10//10 11//11 12//12
+// polygonNormal uses Newell's method to find the normal of a polygon that
+// may not be perfectly flat, pointing out of the side it's wound counter
+// clockwise around.
+func polygonNormal(points []vector.Vector3) vector.Vector3 {
+	x, y, z := 0., 0., 0.
+	for i, current := range points {
+		next := points[(i+1)%len(points)]
+		x += (current.Y() - next.Y()) * (current.Z() + next.Z())
+		y += (current.Z() - next.Z()) * (current.X() + next.X())
+		z += (current.X() - next.X()) * (current.Y() + next.Y())
+	}
 
-	components := strings.Split(str, " ")
+	normal := vector.NewVector3(x, y, z)
+	if normal.Length() == 0 {
+		return normal
+	}
+	return normal.Normalized()
+}
 
-	if len(components) != 3 {
-		return -1, -1, -1, fmt.Errorf("unable to parse: (%s)", str)
+// triangulatePolygon splits a polygon into triangles that keep its
+// winding by clipping ears, which handles concave polygons. Triangles are
+// returned as indices into points.
+func triangulatePolygon(points []vector.Vector3) [][3]int {
+	if len(points) == 3 {
+		return [][3]int{{0, 1, 2}}
 	}
 
-	v1Components := strings.Split(components[0], "/")
-	v1Parse, err := strconv.Atoi(v1Components[0])
-	if err != nil {
-		return -1, -1, -1, errors.New("unable to parse X componenent: " + v1Components[0])
+	// Flatten the polygon onto the plane it mostly lays in, keeping it
+	// wound counter clockwise
+	normal := polygonNormal(points)
+	dropAxis := 2
+	if math.Abs(normal.X()) >= math.Abs(normal.Y()) && math.Abs(normal.X()) >= math.Abs(normal.Z()) {
+		dropAxis = 0
+	} else if math.Abs(normal.Y()) >= math.Abs(normal.Z()) {
+		dropAxis = 1
 	}
 
-	v2Components := strings.Split(components[1], "/")
-	v2Parse, err := strconv.Atoi(v2Components[0])
-	if err != nil {
-		return -1, -1, -1, errors.New("unable to parse Y componenent: " + v2Components[1])
+	flat := make([]vector.Vector2, len(points))
+	for i, p := range points {
+		switch dropAxis {
+		case 0:
+			flat[i] = vector.NewVector2(p.Y(), p.Z())
+		case 1:
+			flat[i] = vector.NewVector2(p.Z(), p.X())
+		default:
+			flat[i] = vector.NewVector2(p.X(), p.Y())
+		}
+	}
+	if signedArea(flat) < 0 {
+		for i := range flat {
+			flat[i] = vector.NewVector2(-flat[i].X(), flat[i].Y())
+		}
 	}
 
-	v3Components := strings.Split(components[2], "/")
-	v3Parse, err := strconv.Atoi(v3Components[0])
-	if err != nil {
-		return -1, -1, -1, errors.New("unable to parse Z componenent: " + v3Components[0])
+	cross := func(a, b, c vector.Vector2) float64 {
+		return ((b.X() - a.X()) * (c.Y() - a.Y())) - ((b.Y() - a.Y()) * (c.X() - a.X()))
+	}
+
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
 	}
 
-	return v1Parse, v2Parse, v3Parse, nil
+	triangles := make([][3]int, 0, len(points)-2)
+	for len(remaining) > 3 {
+		clipped := false
+		for i := range remaining {
+			prev := remaining[(i+len(remaining)-1)%len(remaining)]
+			current := remaining[i]
+			next := remaining[(i+1)%len(remaining)]
+
+			if cross(flat[prev], flat[current], flat[next]) <= 0 {
+				continue
+			}
+
+			ear := []vector.Vector2{flat[prev], flat[current], flat[next]}
+			isEar := true
+			for _, other := range remaining {
+				if other == prev || other == current || other == next {
+					continue
+				}
+				if pointInPolygon(flat[other], ear) {
+					isEar = false
+					break
+				}
+			}
+			if !isEar {
+				continue
+			}
+
+			triangles = append(triangles, [3]int{prev, current, next})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			clipped = true
+			break
+		}
+
+		// Polygons that twist over themselves have no ears left, so fan out
+		// whatever remains
+		if !clipped {
+			for i := 1; i+1 < len(remaining); i++ {
+				triangles = append(triangles, [3]int{remaining[0], remaining[i], remaining[i+1]})
+			}
+			return triangles
+		}
+	}
+
+	return append(triangles, [3]int{remaining[0], remaining[1], remaining[2]})
 }
 
-func importOBJ(objStream io.Reader) (*mesh.Model, error) {
+// objLines reads the logical lines of an OBJ file, joining lines ending in
+// a backslash with the next and stripping comments. Each line comes with
+// the line number it started on.
+func objLines(in io.Reader, handle func(lineNumber int, fields []string) error) error {
+	scanner := bufio.NewScanner(in)
+
+	lineNumber := 0
+	startedOn := 0
+	line := strings.Builder{}
+	for scanner.Scan() {
+		lineNumber++
+		if line.Len() == 0 {
+			startedOn = lineNumber
+		}
+
+		text := scanner.Text()
+		if comment := strings.Index(text, "#"); comment != -1 {
+			text = text[:comment]
+		}
+
+		trimmed := strings.TrimRight(text, " \t\r")
+		if strings.HasSuffix(trimmed, "\\") {
+			line.WriteString(strings.TrimSuffix(trimmed, "\\"))
+			line.WriteString(" ")
+			continue
+		}
+		line.WriteString(text)
+
+		fields := strings.Fields(line.String())
+		line.Reset()
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := handle(startedOn, fields); err != nil {
+			return fmt.Errorf("line %d: %w", startedOn, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if fields := strings.Fields(line.String()); len(fields) > 0 {
+		if err := handle(startedOn, fields); err != nil {
+			return fmt.Errorf("line %d: %w", startedOn, err)
+		}
+	}
+	return nil
+}
+
+// readOBJ reads every face of an OBJ file, split up by the objects, groups
+// and materials they were declared with. Polygons are triangulated, and
+// texture coordinates and normals are kept when every corner of a face has
+// them.
+func readOBJ(objStream io.Reader) (*objFile, error) {
 	if objStream == nil {
 		return nil, errors.New("Need a reader to read obj from")
 	}
 
+	libraries := make([]string, 0)
+
 	vertices := make([]vector.Vector3, 0)
+	textures := make([]vector.Vector2, 0)
+	normals := make([]vector.Vector3, 0)
 
-	faces := make([]mesh.Polygon, 0)
+	groups := []objGroup{{}}
+	current := func() *objGroup { return &groups[len(groups)-1] }
 
-	scanner := bufio.NewScanner(objStream)
-	for scanner.Scan() {
-		ln := scanner.Text()
-		firstWord, rest := firstWords(ln, 1)
-		if firstWord == "v" {
-			vector, err := strToVector(strings.TrimSpace(rest))
+	// Start a new group when a name or material changes, reusing the
+	// current one if nothing's been put in it yet
+	startGroup := func(object, name, material string) {
+		if len(current().Faces) > 0 {
+			groups = append(groups, objGroup{})
+		}
+		current().Object = object
+		current().Name = name
+		current().Material = material
+	}
+
+	err := objLines(objStream, func(lineNumber int, fields []string) error {
+		switch fields[0] {
+		case "v":
+			// Vertices can be followed by a w or by a color, neither of
+			// which are used
+			values, err := parseOBJFloats(fields[1:], 3, 6, 1)
 			if err != nil {
-				return nil, err
+				return fmt.Errorf("v: %w", err)
 			}
-			vertices = append(vertices, *vector)
-		}
+			vertices = append(vertices, vector.NewVector3(values[0], values[1], values[2]))
+
+		case "vt":
+			values, err := parseOBJFloats(fields[1:], 1, 3, 0)
+			if err != nil {
+				return fmt.Errorf("vt: %w", err)
+			}
+			textures = append(textures, vector.NewVector2(values[0], values[1]))
 
-		if firstWord == "f" {
-			v1, v2, v3, err := strToFaceIndexes(strings.TrimSpace(rest))
+		case "vn":
+			values, err := parseOBJFloats(fields[1:], 3, 3, 0)
 			if err != nil {
-				return nil, err
+				return fmt.Errorf("vn: %w", err)
 			}
+			normals = append(normals, vector.NewVector3(values[0], values[1], values[2]))
 
-			p, err := mesh.NewPolygon([]vector.Vector3{
-				vertices[v1-1],
-				vertices[v2-1],
-				vertices[v3-1],
-			}, []vector.Vector3{
-				vertices[v1-1],
-				vertices[v2-1],
-				vertices[v3-1],
-			})
+		case "o":
+			startGroup(strings.Join(fields[1:], " "), "", current().Material)
 
+		case "g":
+			startGroup(current().Object, strings.Join(fields[1:], " "), current().Material)
+
+		case "usemtl":
+			startGroup(current().Object, current().Name, strings.Join(fields[1:], " "))
+
+		case "mtllib":
+			if len(fields) < 2 {
+				return errors.New("mtllib: requires a path")
+			}
+			libraries = append(libraries, fields[1:]...)
+
+		case "f":
+			faces, err := objFace(fields[1:], vertices, textures, normals)
 			if err != nil {
-				return nil, err
+				return fmt.Errorf("f: %w", err)
 			}
+			current().Faces = append(current().Faces, faces...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			faces = append(faces, p)
+	filled := make([]objGroup, 0, len(groups))
+	for _, group := range groups {
+		if len(group.Faces) > 0 {
+			filled = append(filled, group)
 		}
 	}
+	return &objFile{MaterialLibraries: libraries, Groups: filled}, nil
+}
 
-	if scanner.Err() != nil {
-		return nil, scanner.Err()
+// objFace builds the triangles of a single face from its corners.
+func objFace(fields []string, vertices []vector.Vector3, textures []vector.Vector2, normals []vector.Vector3) ([]mesh.Polygon, error) {
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("a face needs at least 3 corners, got %d", len(fields))
 	}
 
-	m, err := mesh.NewModel(faces)
+	corners := make([]objCorner, len(fields))
+	points := make([]vector.Vector3, len(fields))
+	hasTextures := true
+	hasNormals := true
+	for i, field := range fields {
+		corner, err := parseFaceCorner(field, len(vertices), len(textures), len(normals))
+		if err != nil {
+			return nil, err
+		}
+		corners[i] = corner
+		points[i] = vertices[corner.vertex]
+		hasTextures = hasTextures && corner.texture != -1
+		hasNormals = hasNormals && corner.normal != -1
+	}
+
+	faceNormal := polygonNormal(points)
 
+	polys := make([]mesh.Polygon, 0, len(corners)-2)
+	for _, triangle := range triangulatePolygon(points) {
+		triangleVertices := make([]vector.Vector3, 3)
+		triangleNormals := make([]vector.Vector3, 3)
+		triangleTextures := make([]vector.Vector2, 3)
+		for i, index := range triangle {
+			corner := corners[index]
+			triangleVertices[i] = points[index]
+			triangleNormals[i] = faceNormal
+			if hasNormals {
+				triangleNormals[i] = normals[corner.normal]
+			}
+			if hasTextures {
+				triangleTextures[i] = textures[corner.texture]
+			}
+		}
+
+		var poly mesh.Polygon
+		var err error
+		if hasTextures {
+			poly, err = mesh.NewPolygonWithTexture(triangleVertices, triangleNormals, triangleTextures)
+		} else {
+			poly, err = mesh.NewPolygon(triangleVertices, triangleNormals)
+		}
+		if err != nil {
+			return nil, err
+		}
+		polys = append(polys, poly)
+	}
+
+	return polys, nil
+}
+
+// importOBJ reads every face of an OBJ file into a single model.
+func importOBJ(objStream io.Reader) (*mesh.Model, error) {
+	obj, err := readOBJ(objStream)
+	if err != nil {
+		return nil, err
+	}
+
+	faces := make([]mesh.Polygon, 0)
+	for _, group := range obj.Groups {
+		faces = append(faces, group.Faces...)
+	}
+
+	m, err := mesh.NewModel(faces)
 	return &m, err
 }
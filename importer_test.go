@@ -1,37 +1,44 @@
 package main
 
 import (
+	"math"
 	"strings"
 	"testing"
 
+	"github.com/EliCDavis/vector"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestFirstWords(t *testing.T) {
-	first, rest := firstWords("v 1.030902 0.060000 0.000000", 1)
+func TestParseOBJFloats(t *testing.T) {
+	v, err := parseOBJFloats(strings.Fields("1.030902 0.060000 0.000000"), 3, 3, 0)
 
-	assert.Equal(t, first, "v")
-	assert.Equal(t, rest, "1.030902 0.060000 0.000000")
-}
-
-func TestParseVector(t *testing.T) {
-	v, err := strToVector("1.030902 0.060000 0.000000")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1.030902, 0.060000, 0.000000}, v)
 
+	uv, err := parseOBJFloats(strings.Fields("0.5"), 1, 3, 0)
 	assert.NoError(t, err)
-	if assert.NotNil(t, v) {
-		assert.Equal(t, 1.030902, v.X())
-		assert.Equal(t, 0.060000, v.Y())
-		assert.Equal(t, 0.000000, v.Z())
-	}
+	assert.Equal(t, []float64{.5, 0, 0}, uv)
+
+	_, err = parseOBJFloats(strings.Fields("1 x 2"), 3, 3, 0)
+	assert.Error(t, err)
 }
 
-func TestParseFaceIndexes(t *testing.T) {
-	v1, v2, v3, err := strToFaceIndexes("10//10 11//11 12//12")
+func TestParseFaceCorner(t *testing.T) {
+	corner, err := parseFaceCorner("10//10", 12, 0, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, objCorner{vertex: 9, texture: -1, normal: 9}, corner)
 
+	corner, err = parseFaceCorner("-1/-2/3", 12, 4, 3)
 	assert.NoError(t, err)
-	assert.Equal(t, 10, v1)
-	assert.Equal(t, 11, v2)
-	assert.Equal(t, 12, v3)
+	assert.Equal(t, objCorner{vertex: 11, texture: 2, normal: 2}, corner)
+
+	_, err = parseFaceCorner("13", 12, 0, 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "out of range")
+	}
+
+	_, err = parseFaceCorner("0", 12, 0, 0)
+	assert.Error(t, err)
 }
 
 func TestImportObj(t *testing.T) {
@@ -61,7 +68,116 @@ func TestImportObj(t *testing.T) {
 	model, err := importOBJ(strings.NewReader(obj.String()))
 
 	assert.NoError(t, err)
-	if assert.NotNil(t, model) {
-		// assert.Equal(t, 2, model.``)
+	if assert.NotNil(t, model) && assert.Len(t, model.GetFaces(), 2) {
+		face := model.GetFaces()[1]
+		assert.Equal(t, vector.NewVector3(1.025938, 0.060000, 0.101046), face.GetVertices()[1])
+		assert.Equal(t, vector.NewVector2(0.125, 1), face.GetUVs()[1])
+	}
+}
+
+func TestReadOBJGroupsAndPolygons(t *testing.T) {
+	obj, err := readOBJ(strings.NewReader(`# A quad and a concave pentagon
+o shield
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+g front
+f 1 2 3 4 # a quad
+v 2 0 0
+v 4 0 0
+v 4 2 0
+v 3 1.5 0
+v 2 2 0
+g back
+f -5 -4 -3 \
+  -2 -1
+`))
+
+	assert.NoError(t, err)
+	groups := obj.Groups
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "shield", groups[0].Object)
+		assert.Equal(t, "front", groups[0].Name)
+		assert.Len(t, groups[0].Faces, 2)
+
+		assert.Equal(t, "shield", groups[1].Object)
+		assert.Equal(t, "back", groups[1].Name)
+		assert.Len(t, groups[1].Faces, 3)
+
+		// Every triangle keeps the winding of the pentagon, so none of them
+		// can have crossed over the notch cut into it
+		area := 0.
+		for _, face := range groups[1].Faces {
+			vertices := face.GetVertices()
+			flat := []vector.Vector2{
+				vector.NewVector2(vertices[0].X(), vertices[0].Y()),
+				vector.NewVector2(vertices[1].X(), vertices[1].Y()),
+				vector.NewVector2(vertices[2].X(), vertices[2].Y()),
+			}
+			assert.Greater(t, signedArea(flat), 0.)
+			area += signedArea(flat)
+		}
+		assert.InDelta(t, 3.5, area, 1e-9)
+	}
+}
+
+func TestReadOBJReportsLineNumbers(t *testing.T) {
+	_, err := readOBJ(strings.NewReader("v 0 0 0\nv 1 0 0\n\nf 1 2 3\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 4")
+		assert.Contains(t, err.Error(), "vertex index 3 is out of range")
+	}
+
+	_, err = readOBJ(strings.NewReader("v 0 0\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 1")
+	}
+}
+
+func TestReadOBJSplitsGroupsByMaterial(t *testing.T) {
+	obj, err := readOBJ(strings.NewReader(`mtllib logo.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+g star
+usemtl gold
+f 1 2 3
+usemtl silver
+f 1 2 3
+g ribbon
+f 1 2 3
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logo.mtl"}, obj.MaterialLibraries)
+	if assert.Len(t, obj.Groups, 3) {
+		assert.Equal(t, "star", obj.Groups[0].Name)
+		assert.Equal(t, "gold", obj.Groups[0].Material)
+		assert.Equal(t, "star", obj.Groups[1].Name)
+		assert.Equal(t, "silver", obj.Groups[1].Material)
+		assert.Equal(t, "ribbon", obj.Groups[2].Name)
+		assert.Equal(t, "silver", obj.Groups[2].Material)
+	}
+}
+
+func TestPlaceLogoModelUsesScaleAndHeightAsGiven(t *testing.T) {
+	v := [][2]float64{{0, 0}, {3, 0}, {3, 3}, {0, 3}}
+	block, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}, {0, 2, 3}}, 3)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	placed := placeLogoModel(block, logoSpec{Scale: .1, Height: .25, Offset: [2]float64{.2, -.3}})
+	center := placed.GetCenterOfBoundingBox()
+	assert.InDelta(t, .2, center.X(), 1e-9)
+	assert.InDelta(t, .25, center.Y(), 1e-9)
+	assert.InDelta(t, -.3, center.Z(), 1e-9)
+
+	// Scaled evenly, so the cube is still as long along every edge
+	face := placed.GetFaces()[0].GetVertices()
+	for i := range face {
+		side := face[i].Distance(face[(i+1)%len(face)])
+		assert.True(t, math.Abs(side-.3) < 1e-9 || math.Abs(side-.3*math.Sqrt2) < 1e-9, "side %g", side)
 	}
 }
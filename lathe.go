@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// sweepProfile runs the profile all the way around the outline, making a
+// surface like the walls of a vase. Every point of the profile is how far
+// out from the outline (X) and how high up (Y) the surface passes. The
+// surface faces to the right of the direction the profile travels in, so a
+// profile climbing up the outside of a solid faces out and one running in
+// across the top faces up.
+//
+// Textures wrap around the outline once, and run along the profile by how
+// far along it each point is.
+func sweepProfile(outline bodyOutline, profile []vector.Vector2) []mesh.Polygon {
+	if len(profile) < 2 {
+		return nil
+	}
+
+	rings := make([][]vector.Vector2, len(profile))
+	for i, point := range profile {
+		rings[i] = outline(point.X())
+	}
+
+	distances := make([]float64, len(profile))
+	for i := 1; i < len(profile); i++ {
+		distances[i] = distances[i-1] + profile[i].Distance(profile[i-1])
+	}
+	length := distances[len(distances)-1]
+	textureV := func(i int) float64 {
+		if length == 0 {
+			return 0
+		}
+		return distances[i] / length
+	}
+
+	polys := make([]mesh.Polygon, 0)
+	for p := 1; p < len(profile); p++ {
+		bottom, top := rings[p-1], rings[p]
+		bottomHeight, topHeight := profile[p-1].Y(), profile[p].Y()
+		bottomV, topV := textureV(p-1), textureV(p)
+
+		for sideIndex := range bottom {
+			next := (sideIndex + 1) % len(bottom)
+			u := float64(sideIndex) / float64(len(bottom))
+			uNext := float64(sideIndex+1) / float64(len(bottom))
+
+			bottomLeft := vector.NewVector3(bottom[sideIndex].X(), bottomHeight, bottom[sideIndex].Y())
+			topLeft := vector.NewVector3(top[sideIndex].X(), topHeight, top[sideIndex].Y())
+			topRight := vector.NewVector3(top[next].X(), topHeight, top[next].Y())
+			bottomRight := vector.NewVector3(bottom[next].X(), bottomHeight, bottom[next].Y())
+
+			square := makeSquareWithTexture(
+				bottomLeft,
+				topLeft,
+				topRight,
+				bottomRight,
+				vector.NewVector2(u, bottomV),
+				vector.NewVector2(u, topV),
+				vector.NewVector2(uNext, topV),
+				vector.NewVector2(uNext, bottomV),
+			)
+
+			// Where the profile touches the middle of a revolved outline
+			// one side of the square shrinks to a point, leaving a single
+			// triangle
+			if bottom[sideIndex].Distance(bottom[next]) > 0 {
+				polys = append(polys, square[0])
+			}
+			if top[sideIndex].Distance(top[next]) > 0 {
+				polys = append(polys, square[1])
+			}
+		}
+	}
+	return polys
+}
+
+// revolve spins the profile around the Y axis with the given number of
+// sides, where every point of the profile is a radius (X) and a height (Y).
+// Winding follows sweepProfile, so a profile run up the outside of a solid
+// makes a surface facing out. Ends of the profile that don't touch the axis
+// are closed off with flat caps when asked for, making a closed solid out of
+// any profile running from the bottom to the top.
+func revolve(profile []vector.Vector2, resolution int, capStart, capEnd bool) []mesh.Polygon {
+	if len(profile) == 0 {
+		return nil
+	}
+
+	// Caps are the profile carried on in to the axis
+	first := profile[0]
+	last := profile[len(profile)-1]
+	if capStart && math.Abs(first.X()) > 0 {
+		profile = append([]vector.Vector2{vector.NewVector2(0, first.Y())}, profile...)
+	}
+	if capEnd && math.Abs(last.X()) > 0 {
+		profile = append(append([]vector.Vector2{}, profile...), vector.NewVector2(0, last.Y()))
+	}
+
+	return sweepProfile(circleBody(resolution, 0), profile)
+}
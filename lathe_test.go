@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+// faceNormal is the direction the face points, following its winding.
+func faceNormal(face mesh.Polygon) vector.Vector3 {
+	v := face.GetVertices()
+	return v[1].Sub(v[0]).Cross(v[2].Sub(v[0]))
+}
+
+func TestRevolveMakesClosedSolids(t *testing.T) {
+	cylinder := revolve([]vector.Vector2{
+		vector.NewVector2(1, 0),
+		vector.NewVector2(1, 2),
+	}, 16, true, true)
+
+	// A ring of squares for the side and a fan of triangles for each cap
+	assert.Len(t, cylinder, (16*2)+16+16)
+	assertClosedManifold(t, cylinder)
+
+	for _, face := range cylinder {
+		normal := faceNormal(face)
+		center := vector.Vector3Zero()
+		for _, v := range face.GetVertices() {
+			center = center.Add(v.DivByConstant(3))
+		}
+
+		switch {
+		case center.Y() < 1e-9:
+			assert.Less(t, normal.Y(), 0., "bottom faces down")
+		case center.Y() > 2-1e-9:
+			assert.Greater(t, normal.Y(), 0., "top faces up")
+		default:
+			assert.Greater(t, normal.Dot(vector.NewVector3(center.X(), 0, center.Z())), 0., "side faces out")
+		}
+
+		for _, uv := range face.GetUVs() {
+			assert.GreaterOrEqual(t, uv.X(), 0.)
+			assert.LessOrEqual(t, uv.X(), 1.)
+			assert.GreaterOrEqual(t, uv.Y(), 0.)
+			assert.LessOrEqual(t, uv.Y(), 1.)
+		}
+	}
+
+	// Profiles that start and end on the axis need no caps
+	dome := revolve([]vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(1, 0),
+		vector.NewVector2(.7, .7),
+		vector.NewVector2(0, 1),
+	}, 16, true, true)
+	assert.Len(t, dome, 16+(16*2)+16)
+	assertClosedManifold(t, dome)
+}
+
+func TestRevolveLeavesEndsOpenUnlessCapped(t *testing.T) {
+	tube := revolve([]vector.Vector2{
+		vector.NewVector2(1, 0),
+		vector.NewVector2(1, 2),
+	}, 16, false, false)
+	assert.Len(t, tube, 16*2)
+
+	// Every face is part of the side, with nothing across either end
+	for _, face := range tube {
+		assert.InDelta(t, 0., faceNormal(face).Y(), 1e-9)
+	}
+}
+
+func TestSweepProfileWrapsTextureOnce(t *testing.T) {
+	faces := sweepProfile(circleBody(8, 1), []vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(0, 1),
+		vector.NewVector2(-.5, 1),
+	})
+
+	// The first step of the profile is twice as long as the second, so it
+	// takes up two thirds of the texture
+	vs := make(map[float64]bool)
+	for _, face := range faces {
+		for _, uv := range face.GetUVs() {
+			vs[math.Round(uv.Y()*1e9)/1e9] = true
+		}
+	}
+	assert.Equal(t, map[float64]bool{0: true, math.Round(2./3*1e9) / 1e9: true, 1: true}, vs)
+}
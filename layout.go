@@ -0,0 +1,485 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/golang/freetype/truetype"
+)
+
+// textLayout arranges a piece of text on the face of a medal.
+type textLayout struct {
+	// layout turns the text into outlines placed on a face of the given
+	// radius, drawing any characters the font is missing from the fallbacks
+	layout func(text textSpec, parsedFont *truetype.Font, fallbacks []*truetype.Font, faceRadius float64) ([]Outline, error)
+}
+
+// textLayouts are all the layouts a medal spec can select by name.
+var textLayouts = map[string]textLayout{
+	"top-arc": {
+		layout: func(text textSpec, parsedFont *truetype.Font, fallbacks []*truetype.Font, faceRadius float64) ([]Outline, error) {
+			arc := ArcTextLayout{
+				Radius:        textRadius(text, (1-arcTextMargin)*faceRadius-(fontAscent(parsedFont)*text.Scale)),
+				Centered:      true,
+				CenterAngle:   textArcAngle(text, math.Pi/2),
+				Direction:     ArcClockwise,
+				LetterSpacing: text.LetterSpacing,
+				Fallbacks:     fallbacks,
+			}
+			return arc.Layout(text.Text, parsedFont, text.CurveTolerance, text.Scale)
+		},
+	},
+	"bottom-arc": {
+		layout: func(text textSpec, parsedFont *truetype.Font, fallbacks []*truetype.Font, faceRadius float64) ([]Outline, error) {
+			arc := ArcTextLayout{
+				Radius:        textRadius(text, (1-arcTextMargin)*faceRadius-(fontDescent(parsedFont)*text.Scale)),
+				Centered:      true,
+				CenterAngle:   textArcAngle(text, -math.Pi/2),
+				Direction:     ArcCounterClockwise,
+				LetterSpacing: text.LetterSpacing,
+				Fallbacks:     fallbacks,
+			}
+			return arc.Layout(text.Text, parsedFont, text.CurveTolerance, text.Scale)
+		},
+	},
+	"straight": {
+		layout: func(text textSpec, parsedFont *truetype.Font, fallbacks []*truetype.Font, faceRadius float64) ([]Outline, error) {
+			outlines, err := TextToOutlines(text.Text, parsedFont, text.CurveTolerance, text.Scale, straightTextLayout, fallbacks...)
+			if err != nil {
+				return nil, err
+			}
+			return translateOutlines(outlines, outlinesCenter(outlines).MultByConstant(-1)), nil
+		},
+	},
+	"block": {
+		layout: func(text textSpec, parsedFont *truetype.Font, fallbacks []*truetype.Font, faceRadius float64) ([]Outline, error) {
+			block := TextBlockLayout{
+				LineHeight: text.LineHeight,
+				Align:      textAligns[text.Align],
+				Width:      text.Width,
+				Radius:     textRadius(text, (1-arcTextMargin)*faceRadius),
+				Fallbacks:  fallbacks,
+			}
+			if block.Width == 0 {
+				block.Width = 2 * block.Radius
+			}
+			return block.Layout(text.Text, parsedFont, text.CurveTolerance, text.Scale)
+		},
+	},
+}
+
+// arcTextMargin is how much of the face's radius is left between text
+// bent around the face and the rim.
+const arcTextMargin = .05
+
+// textRadius is the radius the spec asks for, or fallback when it doesn't.
+func textRadius(text textSpec, fallback float64) float64 {
+	if text.Radius > 0 {
+		return text.Radius
+	}
+	return fallback
+}
+
+// textArcAngle is the angle in radians the spec asks the text to be
+// centered on, or fallback when it doesn't.
+func textArcAngle(text textSpec, fallback float64) float64 {
+	if text.Angle != nil {
+		return *text.Angle * math.Pi / 180
+	}
+	return fallback
+}
+
+// textLayoutNames lists every layout in textLayouts in alphabetical order.
+func textLayoutNames() []string {
+	names := make([]string, 0, len(textLayouts))
+	for name := range textLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// straightTextLayout lays letters out on a single line, the way
+// TextToShape positions them.
+func straightTextLayout(text TextShape) []mesh.Shape {
+	return text.Shapes()
+}
+
+// fontAscent is how far the tallest glyph of the font rises above the
+// baseline, in the units of the text.
+func fontAscent(parsedFont *truetype.Font) float64 {
+	return fontUnits(parsedFont.Bounds(glyphLoadScale).Max.Y)
+}
+
+// fontDescent is how far the lowest glyph of the font drops below the
+// baseline, in the units of the text.
+func fontDescent(parsedFont *truetype.Font) float64 {
+	return -fontUnits(parsedFont.Bounds(glyphLoadScale).Min.Y)
+}
+
+// ArcDirection is which way text runs around an arc.
+type ArcDirection int
+
+const (
+	// ArcClockwise text runs clockwise with the tops of its letters facing
+	// away from the center, the way text runs over the top of a coin
+	ArcClockwise ArcDirection = iota
+
+	// ArcCounterClockwise text runs counter clockwise with the tops of its
+	// letters facing the center, which keeps text running under the bottom
+	// of a coin upright
+	ArcCounterClockwise
+)
+
+// ArcTextLayout bends a line of text around an arc centered on the middle
+// of a medal's face. Letters are spaced by their advance widths and the
+// font's kerning, and turned so their baselines run along the arc.
+//
+// Angles are in radians, counter clockwise from the right hand side of the
+// face when looking down at it, so the top of the face is at Pi/2.
+type ArcTextLayout struct {
+	// Radius of the arc the baseline of the text sits on
+	Radius float64
+
+	// Centered text is centered on CenterAngle, taking up as much of the
+	// arc as it needs. Otherwise the text starts at StartAngle and is
+	// spread out or squeezed together to end at EndAngle.
+	Centered    bool
+	CenterAngle float64
+	StartAngle  float64
+	EndAngle    float64
+
+	Direction ArcDirection
+
+	// LetterSpacing is added between every letter, in the same units as
+	// the radius
+	LetterSpacing float64
+
+	// Fallbacks are the fonts characters missing from the font are drawn
+	// from, in the order they're tried
+	Fallbacks []*truetype.Font
+}
+
+// Layout writes the text around the arc in the font, scaled by scale,
+// returning outlines ready to be placed on the face of a medal. Curves are
+// flattened to within curveTolerance before the text is scaled.
+func (a ArcTextLayout) Layout(text string, parsedFont *truetype.Font, curveTolerance, scale float64) ([]Outline, error) {
+	if parsedFont == nil {
+		return nil, errors.New("Need a font to write text with")
+	}
+	if a.Radius <= 0 {
+		return nil, fmt.Errorf("arc radius must be greater than 0, got %g", a.Radius)
+	}
+
+	letters, err := TextToShape(text, parsedFont, curveTolerance, a.Fallbacks...)
+	if err != nil {
+		return nil, err
+	}
+	if len(letters.Glyphs) == 0 {
+		return nil, nil
+	}
+
+	// Where each letter starts and how wide it is along the arc
+	starts := make([]float64, len(letters.Glyphs))
+	widths := make([]float64, len(letters.Glyphs))
+	for i, glyph := range letters.Glyphs {
+		starts[i] = (glyph.Origin.X() * scale) + (a.LetterSpacing * float64(i))
+		widths[i] = glyph.Advance * scale
+	}
+	length := starts[len(starts)-1] + widths[len(widths)-1]
+
+	// Clockwise text walks backwards through the angles
+	direction := 1.
+	if a.Direction == ArcClockwise {
+		direction = -1.
+	}
+
+	startAngle := a.StartAngle
+	extraSpacing := 0.
+	if a.Centered {
+		startAngle = a.CenterAngle - (direction * length / (2 * a.Radius))
+	} else {
+		sweep := (a.EndAngle - a.StartAngle) * direction
+		if sweep <= 0 {
+			return nil, errors.New("the end of the arc must be after its start in the direction the text runs")
+		}
+		if len(letters.Glyphs) > 1 {
+			extraSpacing = ((sweep * a.Radius) - length) / float64(len(letters.Glyphs)-1)
+		}
+	}
+
+	shapes := make([]mesh.Shape, 0)
+	for i, glyph := range letters.Glyphs {
+		middle := starts[i] + (extraSpacing * float64(i)) + (widths[i] / 2)
+		angle := startAngle + (direction * middle / a.Radius)
+		anchor := vector.NewVector2(math.Cos(angle), math.Sin(angle)).MultByConstant(a.Radius)
+
+		// Turns the letter's baseline to run along the arc, with its top
+		// facing out for clockwise text and in for counter clockwise
+		tangent := angle + (direction * math.Pi / 2)
+
+		for _, shape := range glyph.Shapes {
+			shapes = append(shapes, shape.
+				Translate(glyph.Origin.MultByConstant(-1)).
+				Scale(scale).
+				Translate(vector.NewVector2(-widths[i]/2, 0)).
+				Rotate(tangent, vector.Vector2Zero()).
+				Translate(anchor))
+		}
+	}
+
+	// Looking down at the face X points left, so the text is mirrored to
+	// read correctly
+	outlines := classifyContours(shapes)
+	for i, outline := range outlines {
+		outlines[i] = outline.MirrorX()
+	}
+	return outlines, nil
+}
+
+// TextAlign is how the lines of a block of text line up with each other.
+type TextAlign int
+
+const (
+	// AlignCenter centers every line on the middle of the block
+	AlignCenter TextAlign = iota
+
+	// AlignLeft lines up the start of every line
+	AlignLeft
+
+	// AlignRight lines up the end of every line
+	AlignRight
+
+	// AlignJustify spreads the words of every line out to the width of the
+	// block, leaving the last line of each paragraph lined up on the left
+	AlignJustify
+)
+
+// textAligns are the alignments a medal spec can select by name.
+var textAligns = map[string]TextAlign{
+	"center":  AlignCenter,
+	"left":    AlignLeft,
+	"right":   AlignRight,
+	"justify": AlignJustify,
+}
+
+// textAlignNames lists every alignment in textAligns in alphabetical order.
+func textAlignNames() []string {
+	names := make([]string, 0, len(textAligns))
+	for name := range textAligns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TextBlockLayout arranges text as a block of lines centered on the middle
+// of a medal's face, for inscriptions that don't follow the rim. Every new
+// line in the text starts a new paragraph.
+type TextBlockLayout struct {
+	// LineHeight is how far apart the baselines of the lines are, as a
+	// multiple of the size of the text
+	LineHeight float64
+
+	Align TextAlign
+
+	// Width lines are wrapped at, breaking them between words. Lines
+	// aren't wrapped when it's 0.
+	Width float64
+
+	// Radius of the circle the whole block is shrunk to fit inside. The
+	// block keeps its size when it's 0.
+	Radius float64
+
+	// Fallbacks are the fonts characters missing from the font are drawn
+	// from, in the order they're tried
+	Fallbacks []*truetype.Font
+}
+
+// blockLine is a single line of a block of text.
+type blockLine struct {
+	text TextShape
+
+	// last lines of a paragraph are never justified
+	last bool
+}
+
+// wrap breaks every paragraph of the text into lines no wider than the
+// layout's width, keeping words that are too wide by themselves on a line
+// of their own. Every word is shaped once and lines are measured from the
+// words and the space between them, so only the finished lines are shaped
+// again as a whole.
+func (b TextBlockLayout) wrap(text string, parsedFont *truetype.Font, curveTolerance, scale float64) ([]blockLine, error) {
+	fonts := append([]*truetype.Font{parsedFont}, b.Fallbacks...)
+	missing := make([]rune, 0)
+
+	lines := make([]blockLine, 0)
+	addLine := func(words []string, last bool) error {
+		shape, err := shapeText(strings.Join(words, " "), parsedFont, curveTolerance, b.Fallbacks...)
+		if err != nil {
+			return err
+		}
+		for _, char := range shape.Missing {
+			if !containsRune(missing, char) {
+				missing = append(missing, char)
+			}
+		}
+		lines = append(lines, blockLine{text: shape, last: last})
+		return nil
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, blockLine{last: true})
+			continue
+		}
+
+		widths := make([]float64, len(words))
+		for i, word := range words {
+			shape, err := shapeText(word, parsedFont, curveTolerance, b.Fallbacks...)
+			if err != nil {
+				return nil, err
+			}
+			widths[i] = shape.Width
+		}
+
+		start := 0
+		width := widths[0]
+		for i := 1; i < len(words); i++ {
+			candidate := width + wordSpacing(fonts, words[i-1], words[i]) + widths[i]
+			if b.Width > 0 && candidate*scale > b.Width {
+				if err := addLine(words[start:i], false); err != nil {
+					return nil, err
+				}
+				start, width = i, widths[i]
+				continue
+			}
+			width = candidate
+		}
+		if err := addLine(words[start:], true); err != nil {
+			return nil, err
+		}
+	}
+
+	logMissingGlyphs(missing, parsedFont, b.Fallbacks)
+	return lines, nil
+}
+
+// wordSpacing is how far the end of one word is from the start of the next
+// with a space between them, which is the space's advance plus any kerning
+// either side of it.
+func wordSpacing(fonts []*truetype.Font, before, after string) float64 {
+	spaceFont, _ := glyphFont(fonts, ' ')
+	space := spaceFont.Index(' ')
+	spacing := fontUnits(spaceFont.HMetric(glyphLoadScale, space).AdvanceWidth)
+
+	// Only letters drawn from the same font can be kerned together
+	last, _ := utf8.DecodeLastRuneInString(before)
+	if lastFont, _ := glyphFont(fonts, last); lastFont == spaceFont {
+		spacing += fontUnits(spaceFont.Kern(glyphLoadScale, lastFont.Index(last), space))
+	}
+	first, _ := utf8.DecodeRuneInString(after)
+	if firstFont, _ := glyphFont(fonts, first); firstFont == spaceFont {
+		spacing += fontUnits(spaceFont.Kern(glyphLoadScale, space, firstFont.Index(first)))
+	}
+	return spacing
+}
+
+// Layout writes the text as a block of lines in the font, scaled by scale,
+// returning outlines ready to be placed on the face of a medal. Curves are
+// flattened to within curveTolerance before the text is scaled.
+func (b TextBlockLayout) Layout(text string, parsedFont *truetype.Font, curveTolerance, scale float64) ([]Outline, error) {
+	if parsedFont == nil {
+		return nil, errors.New("Need a font to write text with")
+	}
+	if b.LineHeight <= 0 {
+		return nil, fmt.Errorf("line height must be greater than 0, got %g", b.LineHeight)
+	}
+
+	lines, err := b.wrap(text, parsedFont, curveTolerance, scale)
+	if err != nil {
+		return nil, err
+	}
+
+	blockWidth := 0.
+	for _, line := range lines {
+		blockWidth = math.Max(blockWidth, line.text.Width*scale)
+	}
+
+	// Lines are spaced down from the first baseline, and then the whole
+	// block is moved so the middle of its lines sits on the center
+	ascent := fontAscent(parsedFont) * scale
+	descent := fontDescent(parsedFont) * scale
+	spacing := b.LineHeight * scale
+	middle := (ascent - (float64(len(lines)-1) * spacing) - descent) / 2
+
+	shapes := make([]mesh.Shape, 0)
+	furthest := 0.
+	for i, line := range lines {
+		baseline := -(float64(i) * spacing) - middle
+		width := line.text.Width * scale
+
+		spaces := 0
+		for _, glyph := range line.text.Glyphs {
+			if strings.TrimSpace(glyph.Text) == "" {
+				spaces++
+			}
+		}
+
+		left := -width / 2
+		extraSpacing := 0.
+		switch b.Align {
+		case AlignLeft:
+			left = -blockWidth / 2
+		case AlignRight:
+			left = (blockWidth / 2) - width
+		case AlignJustify:
+			left = -blockWidth / 2
+			if !line.last && spaces > 0 {
+				extraSpacing = (blockWidth - width) / float64(spaces)
+				width = blockWidth
+			}
+		}
+
+		if len(line.text.Glyphs) > 0 {
+			furthest = math.Max(furthest, math.Hypot(
+				math.Max(math.Abs(left), math.Abs(left+width)),
+				math.Max(math.Abs(baseline+ascent), math.Abs(baseline-descent)),
+			))
+		}
+
+		spacesBefore := 0
+		for _, glyph := range line.text.Glyphs {
+			offset := vector.NewVector2(left+(extraSpacing*float64(spacesBefore)), baseline)
+			for _, shape := range glyph.Shapes {
+				shapes = append(shapes, shape.Scale(scale).Translate(offset))
+			}
+			if strings.TrimSpace(glyph.Text) == "" {
+				spacesBefore++
+			}
+		}
+	}
+
+	// Every line is measured from the center, so shrinking the block down
+	// keeps it centered
+	if b.Radius > 0 && furthest > b.Radius {
+		for i, shape := range shapes {
+			shapes[i] = shape.Scale(b.Radius / furthest)
+		}
+	}
+
+	// Looking down at the face X points left, so the text is mirrored to
+	// read correctly
+	outlines := classifyContours(shapes)
+	for i, outline := range outlines {
+		outlines[i] = outline.MirrorX()
+	}
+	return outlines, nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// screenCenter is the center of the outline as it's seen looking down at
+// the face, with X pointing right.
+func screenCenter(outline Outline) (float64, float64) {
+	min, max := outline.Outer.GetBounds()
+	return -(min.X() + max.X()) / 2, (min.Y() + max.Y()) / 2
+}
+
+func largestOutline(outlines []Outline) Outline {
+	largest := outlines[0]
+	for _, outline := range outlines[1:] {
+		if math.Abs(signedArea(outline.Outer.GetPoints())) > math.Abs(signedArea(largest.Outer.GetPoints())) {
+			largest = outline
+		}
+	}
+	return largest
+}
+
+func TestArcTextLayoutClockwiseRunsOverTheTop(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	arc := ArcTextLayout{Radius: 1, Centered: true, CenterAngle: math.Pi / 2, Direction: ArcClockwise}
+	outlines, err := arc.Layout("T_", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	if !assert.Len(t, outlines, 2) {
+		return
+	}
+
+	// The T comes first, so it's on the left looking down at the face
+	x, y := screenCenter(largestOutline(outlines))
+	assert.Less(t, x, 0.)
+	assert.Greater(t, y, 0.)
+
+	// Letters stand on the arc with their tops facing out
+	for _, outline := range outlines {
+		for _, p := range outline.Outer.GetPoints() {
+			assert.GreaterOrEqual(t, p.Length(), 1-(fontDescent(parsedFont)*.2))
+			assert.LessOrEqual(t, p.Length(), 1+(fontAscent(parsedFont)*.2))
+		}
+	}
+}
+
+func TestArcTextLayoutCounterClockwiseStaysUprightAtTheBottom(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	arc := ArcTextLayout{Radius: 1, Centered: true, CenterAngle: -math.Pi / 2, Direction: ArcCounterClockwise}
+	outlines, err := arc.Layout("T_", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	if !assert.Len(t, outlines, 2) {
+		return
+	}
+
+	x, y := screenCenter(largestOutline(outlines))
+	assert.Less(t, x, 0.)
+	assert.Less(t, y, 0.)
+
+	// Tops face the center, so nothing rises past the baseline
+	for _, outline := range outlines {
+		for _, p := range outline.Outer.GetPoints() {
+			assert.LessOrEqual(t, p.Length(), 1+(fontDescent(parsedFont)*.2))
+		}
+	}
+}
+
+func TestArcTextLayoutSpreadsTextBetweenAngles(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	arc := ArcTextLayout{Radius: 1, StartAngle: math.Pi, EndAngle: 0, Direction: ArcClockwise}
+	outlines, err := arc.Layout("T_", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	if !assert.Len(t, outlines, 2) {
+		return
+	}
+
+	// The T starts at the far left and the underscore ends at the far right
+	x, _ := screenCenter(largestOutline(outlines))
+	assert.Less(t, x, -.8)
+	for _, outline := range outlines {
+		if x, _ := screenCenter(outline); x > 0 {
+			assert.Greater(t, x, .8)
+		}
+	}
+
+	arc.EndAngle = 2 * math.Pi
+	_, err = arc.Layout("T_", parsedFont, defaultCurveTolerance, .2)
+	assert.EqualError(t, err, "the end of the arc must be after its start in the direction the text runs")
+}
+
+func TestArcTextLayoutSpacesLettersByAdvance(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Spaces have no outlines but still push the letters after them along
+	tight := ArcTextLayout{Radius: 1, Centered: true, CenterAngle: math.Pi / 2}
+	loose := tight
+	loose.LetterSpacing = .1
+
+	spread := func(arc ArcTextLayout, text string) float64 {
+		outlines, err := arc.Layout(text, parsedFont, defaultCurveTolerance, .2)
+		assert.NoError(t, err)
+		min, max := outlinesBounds(outlines)
+		return max.X() - min.X()
+	}
+
+	assert.Greater(t, spread(tight, "i  i"), spread(tight, "ii"))
+	assert.Greater(t, spread(loose, "ii"), spread(tight, "ii"))
+}
+
+// screenSpan is how far the outlines reach left and right looking down at
+// the face, with X pointing right.
+func screenSpan(outlines []Outline) (float64, float64) {
+	min, max := outlinesBounds(outlines)
+	return -max.X(), -min.X()
+}
+
+// splitLines separates outlines above the middle of the face from those
+// below it.
+func splitLines(outlines []Outline) ([]Outline, []Outline) {
+	above := make([]Outline, 0)
+	below := make([]Outline, 0)
+	for _, outline := range outlines {
+		if _, y := screenCenter(outline); y > 0 {
+			above = append(above, outline)
+		} else {
+			below = append(below, outline)
+		}
+	}
+	return above, below
+}
+
+func TestTextBlockLayoutStacksLines(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	block := TextBlockLayout{LineHeight: 1.2, Align: AlignLeft}
+	outlines, err := block.Layout("T\nTTT", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+
+	above, below := splitLines(outlines)
+	if !assert.Len(t, above, 1) || !assert.Len(t, below, 3) {
+		return
+	}
+
+	// Left aligned lines start together
+	aboveLeft, _ := screenSpan(above)
+	belowLeft, _ := screenSpan(below)
+	assert.InDelta(t, belowLeft, aboveLeft, 1e-9)
+
+	block.Align = AlignRight
+	outlines, err = block.Layout("T\nTTT", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	above, below = splitLines(outlines)
+	_, aboveRight := screenSpan(above)
+	_, belowRight := screenSpan(below)
+	assert.InDelta(t, belowRight, aboveRight, 1e-9)
+}
+
+func TestTextBlockLayoutWrapsAndJustifies(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	long, err := TextToShape("TTTTTT", parsedFont, defaultCurveTolerance)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Everything fits on one line until it's too wide
+	block := TextBlockLayout{LineHeight: 1.2, Align: AlignJustify}
+	outlines, err := block.Layout("T T TTTTTT", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	above, below := splitLines(outlines)
+	assert.Len(t, above, 8)
+	assert.Empty(t, below)
+
+	block.Width = (long.Width * .2) + .01
+	outlines, err = block.Layout("T T TTTTTT", parsedFont, defaultCurveTolerance, .2)
+	assert.NoError(t, err)
+	above, below = splitLines(outlines)
+	if !assert.Len(t, above, 2) || !assert.Len(t, below, 6) {
+		return
+	}
+
+	// The first line is spread out to be as wide as the last
+	aboveLeft, aboveRight := screenSpan(above)
+	belowLeft, belowRight := screenSpan(below)
+	assert.InDelta(t, belowLeft, aboveLeft, 1e-9)
+	assert.InDelta(t, belowRight, aboveRight, 1e-9)
+}
+
+func TestTextBlockLayoutShrinksToFit(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	block := TextBlockLayout{LineHeight: 1.2, Radius: .5}
+	outlines, err := block.Layout("Employee\nTTTTTTTTTT", parsedFont, defaultCurveTolerance, .4)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, outlines)
+	for _, outline := range outlines {
+		for _, p := range outline.Outer.GetPoints() {
+			assert.LessOrEqual(t, p.Length(), .5+1e-9)
+		}
+	}
+
+	// Text that already fits is left the size it is
+	small := TextBlockLayout{LineHeight: 1.2, Radius: 10}
+	fitted, err := small.Layout("T", parsedFont, defaultCurveTolerance, .4)
+	assert.NoError(t, err)
+	unfitted, err := TextBlockLayout{LineHeight: 1.2}.Layout("T", parsedFont, defaultCurveTolerance, .4)
+	assert.NoError(t, err)
+	assert.Equal(t, unfitted, fitted)
+}
+
+func TestTextBlockLayoutWrapsWhereShapedLinesWouldOverflow(t *testing.T) {
+	parsedFont, err := loadFont("sample.ttf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	logged := bytes.Buffer{}
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	text := "Awarded to Tawny Volkov for a year of Avid Work ☃ ☃"
+	for _, width := range []float64{1, 1.5, 2.5} {
+		block := TextBlockLayout{LineHeight: 1.2, Width: width}
+		lines, err := block.wrap(text, parsedFont, defaultCurveTolerance, .2)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// Measured lines come out the same as shaping each candidate line
+		// would have wrapped them
+		words := strings.Fields(text)
+		for i, line := range lines {
+			count := len(strings.Fields(glyphText(line.text)))
+			if count > 1 {
+				assert.LessOrEqual(t, line.text.Width*.2, width)
+			}
+			if i < len(lines)-1 {
+				overflow, err := shapeText(strings.Join(words[:count+1], " "), parsedFont, defaultCurveTolerance)
+				assert.NoError(t, err)
+				assert.Greater(t, overflow.Width*.2, width)
+			}
+			words = words[count:]
+		}
+		assert.Empty(t, words)
+	}
+
+	// Missing glyphs are reported once for the whole block
+	assert.Equal(t, 3, strings.Count(logged.String(), "no glyph for"))
+}
+
+func glyphText(shape TextShape) string {
+	text := strings.Builder{}
+	for _, glyph := range shape.Glyphs {
+		text.WriteString(glyph.Text)
+	}
+	return text.String()
+}
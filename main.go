@@ -4,17 +4,19 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/EliCDavis/mesh"
 	"github.com/EliCDavis/vector"
 	"github.com/golang/freetype/truetype"
 	"github.com/pradeep-pyro/triangle"
-	"golang.org/x/image/font"
+	"github.com/rivo/uniseg"
 )
 
 func makeSquareWithTexture(
@@ -48,11 +50,18 @@ func makeSquareWithTexture(
 	return polys
 }
 
-func fill(shapes []mesh.Shape) ([]mesh.Polygon, error) {
+// triangulateOutlines triangulates the area covered by the outlines,
+// leaving their holes empty. Faces index into the vertices returned.
+func triangulateOutlines(outlines []Outline) ([][2]float64, [][3]int32, error) {
+
+	shapes := make([]mesh.Shape, 0, len(outlines))
+	for _, outline := range outlines {
+		shapes = append(shapes, outline.Shapes()...)
+	}
 
 	for _, shape := range shapes {
 		if len(shape.GetPoints()) < 3 {
-			return nil, errors.New("Can't make a polygon with less than 3 points")
+			return nil, nil, errors.New("Can't make a polygon with less than 3 points")
 		}
 	}
 
@@ -83,13 +92,22 @@ func fill(shapes []mesh.Shape) ([]mesh.Polygon, error) {
 	}
 
 	// Hole represented by a point lying inside it
-	var holes = make([][2]float64, len(shapes))
-	for i := range shapes {
-		holes[i][0] = 0
-		holes[i][1] = 0.0
+	holes, err := holePoints(outlines)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	v, faces := triangle.ConstrainedDelaunay(flatPoints, segments, holes)
+	return v, faces, nil
+}
+
+// fill triangulates the area covered by the outlines, leaving their holes
+// empty. Triangles are laid flat on the XZ plane.
+func fill(outlines []Outline) ([]mesh.Polygon, error) {
+	v, faces, err := triangulateOutlines(outlines)
+	if err != nil {
+		return nil, err
+	}
 
 	betterPolys := make([]mesh.Polygon, len(faces))
 	for i, face := range faces {
@@ -103,72 +121,65 @@ func fill(shapes []mesh.Shape) ([]mesh.Polygon, error) {
 	return betterPolys, nil
 }
 
-func makeBottomPlate(resolution int, radius float64) []mesh.Polygon {
-	polys := make([]mesh.Polygon, resolution)
-
-	angleIncrement := (1.0 / float64(resolution)) * 2.0 * math.Pi
-	for sideIndex := 0; sideIndex < resolution; sideIndex++ {
-		angle := angleIncrement * float64(sideIndex)
-		angleNext := angleIncrement * (float64(sideIndex) + 1)
-
-		points := []vector.Vector3{
-			vector.NewVector3(math.Cos(angle)*radius, 0, math.Sin(angle)*radius),
-			vector.NewVector3(math.Cos(angleNext)*radius, 0, math.Sin(angleNext)*radius),
-			vector.NewVector3(0, 0, 0),
-		}
-
-		tex := []vector.Vector2{
-			vector.NewVector2(0, 0),
-			vector.NewVector2(1, 0),
-			vector.NewVector2(1, 1),
-		}
-
-		poly, _ := mesh.NewPolygonWithTexture(points, points, tex)
-
-		polys[sideIndex] = poly
+// makeBottomPlate closes off the bottom of a medal inside the outline,
+// facing down.
+func makeBottomPlate(outline []vector.Vector2) ([]mesh.Polygon, error) {
+	top, err := carve(outline, nil, 0)
+	if err != nil {
+		return nil, err
+	}
 
+	plate, err := mesh.NewModel(top)
+	if err != nil {
+		return nil, err
 	}
 
-	return polys
+	bottom, err := flipWinding(plate)
+	if err != nil {
+		return nil, err
+	}
+	return bottom.GetFaces(), nil
 }
 
-func makeTopPlate(resolution int, radius, height float64) []mesh.Polygon {
-	polys := make([]mesh.Polygon, resolution)
-
-	angleIncrement := (1.0 / float64(resolution)) * 2.0 * math.Pi
-	for sideIndex := 0; sideIndex < resolution; sideIndex++ {
-		angle := angleIncrement * float64(sideIndex)
-		angleNext := angleIncrement * (float64(sideIndex) + 1)
-
-		points := []vector.Vector3{
-			vector.NewVector3(0, height, 0),
-			vector.NewVector3(math.Cos(angleNext)*radius, height, math.Sin(angleNext)*radius),
-			vector.NewVector3(math.Cos(angle)*radius, height, math.Sin(angle)*radius),
-		}
-
-		tex := []vector.Vector2{
-			vector.NewVector2(0, 0),
-			vector.NewVector2(1, 0),
-			vector.NewVector2(1, 1),
-		}
-
-		poly, _ := mesh.NewPolygonWithTexture(points, points, tex)
+// carve triangulates the area inside the outer contour with every cutout
+// removed from it, at the given height. Any holes of a cutout are left
+// standing as islands. Faces point up and are textured as if the texture
+// was stretched over the bounds of the outer contour.
+func carve(outer []vector.Vector2, cutouts []Outline, height float64) ([]mesh.Polygon, error) {
 
-		polys[sideIndex] = poly
+	if len(outer) < 3 {
+		return nil, errors.New("Can't make a polygon with less than 3 points")
 	}
 
-	return polys
-}
-
-func carve(width float64, height float64, shapes []mesh.Shape) ([]mesh.Polygon, error) {
+	shapes := make([]mesh.Shape, 0, len(cutouts))
+	for _, cutout := range cutouts {
+		shapes = append(shapes, cutout.Shapes()...)
+	}
 
 	for _, shape := range shapes {
 		if len(shape.GetPoints()) < 3 {
 			return nil, errors.New("Can't make a polygon with less than 3 points")
 		}
+		for _, p := range shape.GetPoints() {
+			if !pointInPolygon(p, outer) {
+				return nil, fmt.Errorf("cutout at (%g, %g) falls outside of the outline being carved", p.X(), p.Y())
+			}
+		}
+
+		// On a concave outline a cutout can cross the border between two
+		// points that are both inside it
+		points := shape.GetPoints()
+		for i, start := range points {
+			end := points[(i+1)%len(points)]
+			for j, corner := range outer {
+				if segmentsCross(start, end, corner, outer[(j+1)%len(outer)]) {
+					return nil, fmt.Errorf("cutout from (%g, %g) to (%g, %g) crosses the outline being carved", start.X(), start.Y(), end.X(), end.Y())
+				}
+			}
+		}
 	}
 
-	numOfPoints := 4
+	numOfPoints := len(outer)
 	pointsPrefixSum := make([]int, len(shapes))
 	for i, shape := range shapes {
 		pointsPrefixSum[i] = numOfPoints
@@ -176,11 +187,12 @@ func carve(width float64, height float64, shapes []mesh.Shape) ([]mesh.Polygon,
 	}
 
 	flatPoints := make([][2]float64, numOfPoints)
+	segments := make([][2]int32, numOfPoints)
 
-	flatPoints[0] = [2]float64{0.0, 0.0}
-	flatPoints[1] = [2]float64{0.0, height}
-	flatPoints[2] = [2]float64{width, height}
-	flatPoints[3] = [2]float64{width, 0.0}
+	for i, point := range outer {
+		flatPoints[i] = [2]float64{point.X(), point.Y()}
+		segments[i] = [2]int32{int32(i), int32((i + 1) % len(outer))}
+	}
 
 	for shapeIndex, shape := range shapes {
 		for pointIndex, point := range shape.GetPoints() {
@@ -189,13 +201,6 @@ func carve(width float64, height float64, shapes []mesh.Shape) ([]mesh.Polygon,
 		}
 	}
 
-	segments := make([][2]int32, numOfPoints)
-
-	segments[0] = [2]int32{0, 1}
-	segments[1] = [2]int32{1, 2}
-	segments[2] = [2]int32{2, 3}
-	segments[3] = [2]int32{3, 0}
-
 	for shapeIndex, shape := range shapes {
 		for pointIndex := 0; pointIndex < len(shape.GetPoints()); pointIndex++ {
 			i := pointIndex + pointsPrefixSum[shapeIndex]
@@ -204,23 +209,44 @@ func carve(width float64, height float64, shapes []mesh.Shape) ([]mesh.Polygon,
 		}
 	}
 
-	// Hole represented by a point lying inside it
-	var holes = make([][2]float64, len(shapes))
-	for i, shape := range shapes {
-		pointInShape := shape.RandomPointInShape()
+	// Hole represented by a point lying inside it, which for a cutout is
+	// anywhere inside its outer contour that's not inside one of its holes
+	var holes = make([][2]float64, len(cutouts))
+	for i, cutout := range cutouts {
+		contours := make([][]vector.Vector2, 0, len(cutout.Holes)+1)
+		for _, shape := range cutout.Shapes() {
+			contours = append(contours, shape.GetPoints())
+		}
+
+		pointInShape, err := interiorPoint(contours)
+		if err != nil {
+			return nil, err
+		}
 		holes[i][0] = pointInShape.X()
 		holes[i][1] = pointInShape.Y()
 	}
 
 	v, faces := triangle.ConstrainedDelaunay(flatPoints, segments, holes)
 
+	outerShape, err := mesh.NewShape(outer)
+	if err != nil {
+		return nil, err
+	}
+	min, max := outerShape.GetBounds()
+	size := max.Sub(min)
+
 	betterPolys := make([]mesh.Polygon, len(faces))
-	for i, face := range faces {
+	for i, face := range orientUp(v, faces) {
 		ourVerts := make([]vector.Vector3, 3)
-		ourVerts[0] = vector.NewVector3(v[face[0]][0], 0, v[face[0]][1])
-		ourVerts[1] = vector.NewVector3(v[face[1]][0], 0, v[face[1]][1])
-		ourVerts[2] = vector.NewVector3(v[face[2]][0], 0, v[face[2]][1])
-		poly, _ := mesh.NewPolygon(ourVerts, ourVerts)
+		uvs := make([]vector.Vector2, 3)
+		for corner, index := range face {
+			ourVerts[corner] = vector.NewVector3(v[index][0], height, v[index][1])
+			uvs[corner] = vector.NewVector2((v[index][0]-min.X())/size.X(), (v[index][1]-min.Y())/size.Y())
+		}
+		poly, err := mesh.NewPolygonWithTexture(ourVerts, ourVerts, uvs)
+		if err != nil {
+			return nil, err
+		}
 		betterPolys[i] = poly
 	}
 	return betterPolys, nil
@@ -228,207 +254,542 @@ func carve(width float64, height float64, shapes []mesh.Shape) ([]mesh.Polygon,
 
 // makeRing makes a single ring of faces.
 func makeRing(resolution int, startingHeight, endingHeight, bottomRadius, topRadius float64) []mesh.Polygon {
-	polys := make([]mesh.Polygon, resolution*2)
-
-	numTimesForTextureToRepeat := 8
-
-	angleIncrement := (1.0 / float64(resolution)) * 2.0 * math.Pi
-	for sideIndex := 0; sideIndex < resolution; sideIndex++ {
-		angle := angleIncrement * float64(sideIndex)
-		angleNext := angleIncrement * (float64(sideIndex) + 1)
-
-		resPerTextToRepeat := resolution / numTimesForTextureToRepeat
-		bottomLeftUV := vector.NewVector2(math.Min(float64(sideIndex)/float64(resPerTextToRepeat), 1), 0)
-		topLeftUV := vector.NewVector2(math.Min(float64(sideIndex)/float64(resPerTextToRepeat), 1), 1.0)
-		topRightUV := vector.NewVector2(math.Min(float64(sideIndex+1)/float64(resPerTextToRepeat), 1), 1.0)
-		bottomRightUV := vector.NewVector2(math.Min(float64(sideIndex+1)/float64(resPerTextToRepeat), 1), 0)
-
-		// outer
-		square := makeSquareWithTexture(
-			vector.NewVector3(math.Cos(angle)*bottomRadius, startingHeight, math.Sin(angle)*bottomRadius),
-			vector.NewVector3(math.Cos(angle)*topRadius, endingHeight, math.Sin(angle)*topRadius),
-			vector.NewVector3(math.Cos(angleNext)*topRadius, endingHeight, math.Sin(angleNext)*topRadius),
-			vector.NewVector3(math.Cos(angleNext)*bottomRadius, startingHeight, math.Sin(angleNext)*bottomRadius),
-			bottomLeftUV,
-			topLeftUV,
-			topRightUV,
-			bottomRightUV,
-		)
-
-		polys[(sideIndex * 2)] = square[0]
-		polys[(sideIndex*2)+1] = square[1]
-
-	}
-
-	return polys
+	return revolve([]vector.Vector2{
+		vector.NewVector2(bottomRadius, startingHeight),
+		vector.NewVector2(topRadius, endingHeight),
+	}, resolution, false, false)
 }
 
+// Reverse reverses the order of the letters in s, keeping accents and
+// other combining marks on the letters they belong to.
 func Reverse(s string) string {
-	runes := []rune(s)
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
+	clusters := make([]string, 0, len(s))
+	graphemes := uniseg.NewGraphemes(s)
+	for graphemes.Next() {
+		clusters = append(clusters, graphemes.Str())
+	}
+	for i, j := 0, len(clusters)-1; i < j; i, j = i+1, j-1 {
+		clusters[i], clusters[j] = clusters[j], clusters[i]
 	}
-	return string(runes)
+	return strings.Join(clusters, "")
 }
 
 // MakeMedalion creates a 3D object that represents a medal
-func MakeMedalion(startingRadius, medalionThickness, designImpression float64) (mesh.Model, error) {
+func MakeMedalion(startingRadius, medalionThickness, designImpression, ringBorder float64, engravings ...engraving) (mesh.Model, error) {
+	side := sineProfile(defaultBulge, defaultBulgeResolution, medalionThickness)
+	body, rim, _, err := makeMedalionParts(circleBody(circleSides, startingRadius), side, designImpression, ringBorder, engravedFace(engravings), nil)
+	if err != nil {
+		return mesh.Model{}, err
+	}
+	return body.Merge(rim), nil
+}
+
+// circleSides is how many lines we will use to "draw" a circle
+const circleSides = 64
+
+// defaultBulge is how much extra radius will be added to the side of the
+// medal as it bulges
+const defaultBulge = .1
+
+// defaultBulgeResolution is how many rings we will use to aproximate the
+// side of the medal bulging out
+const defaultBulgeResolution = 10
+
+// medalReverse is the back of a double sided medal, with a design face
+// sunk impression into the bottom of the body inside a rim border wide.
+type medalReverse struct {
+	impression float64
+	border     float64
+	face       medalFace
+}
+
+// makeMedalionParts builds the medalion in the shape of the outline with
+// its side following the profile, split into the body, which is the sides,
+// back and design faces, the rim raised around the face, and the rim around
+// the reverse face. The top of the profile is the top of the rim. Medals
+// without a reverse have a flat back and no reverse rim.
+func makeMedalionParts(outline bodyOutline, side []vector.Vector2, designImpression, ringBorder float64, designFace medalFace, reverse *medalReverse) (mesh.Model, mesh.Model, mesh.Model, error) {
 
 	defer timeTrack(time.Now(), "Creating Medal")
 
-	// How much extra radius will be added to the side of the medal as it bulges
-	maxRadiusBulge := .1
+	if len(side) < 2 {
+		return mesh.Model{}, mesh.Model{}, mesh.Model{}, errors.New("the side of a medal needs at least 2 points")
+	}
+	bottomOfSide := side[0]
+	topOfSide := side[len(side)-1]
+	medalionThickness := topOfSide.Y()
 
-	// how many rings we will use to aproximate the side of the medal bulging out
-	bulgeResolution := 10
+	polys := sweepProfile(outline, side)
 
-	// How many lines we will use to "draw" a circle
-	sides := 64
+	reverseRim := mesh.Model{}
+	if reverse == nil {
+		bottom, err := makeBottomPlate(outline(bottomOfSide.X()))
+		if err != nil {
+			return mesh.Model{}, mesh.Model{}, mesh.Model{}, err
+		}
+		polys = append(polys, bottom...)
+	} else {
+		face, rim, err := makeReverse(outline, bottomOfSide, *reverse)
+		if err != nil {
+			return mesh.Model{}, mesh.Model{}, mesh.Model{}, err
+		}
+		polys = append(polys, face.GetFaces()...)
+		reverseRim = rim
+	}
 
-	polys := make([]mesh.Polygon, 0)
+	// The rim runs in from the top of the side and drops down to the face
+	rimPolys := sweepProfile(outline, []vector.Vector2{
+		topOfSide,
+		vector.NewVector2(-ringBorder, medalionThickness),
+		vector.NewVector2(-ringBorder, medalionThickness-designImpression),
+	})
 
-	sinIncrement := 1. / float64(bulgeResolution)
-	ringHeightIncrement := medalionThickness * sinIncrement
-	for b := 0.; b < float64(bulgeResolution); b += 1.0 {
-		// resolution int, startingHeight, endingHeight, bottomRadius, topRadius float64
-		polys = append(polys, makeRing(
-			sides,
-			ringHeightIncrement*b,
-			ringHeightIncrement*(b+1),
-			(maxRadiusBulge*math.Sin(math.Pi*sinIncrement*b))+startingRadius,
-			(math.Sin(math.Pi*sinIncrement*(b+1))*maxRadiusBulge)+startingRadius)...)
+	face, err := designFace(outline(-ringBorder), medalionThickness-designImpression)
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, mesh.Model{}, err
 	}
+	polys = append(polys, face...)
 
-	polys = append(polys, makeBottomPlate(sides, startingRadius)...)
+	body, err := mesh.NewModel(polys)
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, mesh.Model{}, err
+	}
 
-	ringBorder := 0.05
-	polys = append(polys, makeRing(sides, medalionThickness, medalionThickness, startingRadius, startingRadius-ringBorder)...)
-	polys = append(polys, makeRing(sides, medalionThickness, medalionThickness-designImpression, startingRadius-ringBorder, startingRadius-ringBorder)...)
-	polys = append(polys, makeTopPlate(sides, startingRadius-ringBorder, medalionThickness-designImpression)...)
+	rim, err := mesh.NewModel(rimPolys)
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, mesh.Model{}, err
+	}
 
-	return mesh.NewModel(polys)
+	return body, rim, reverseRim, nil
 }
 
-func TextToShape(textToWrite string) ([][]mesh.Shape, error) {
+// makeReverse builds the reverse face and its rim, starting from the
+// bottom of the side. They're made the same way as the front, on the
+// outline as it's seen from below, and then turned over onto the back of
+// the medal.
+func makeReverse(outline bodyOutline, bottomOfSide vector.Vector2, reverse medalReverse) (mesh.Model, mesh.Model, error) {
+	seenFromBelow := mirroredBody(outline)
+	bottom := -bottomOfSide.Y()
+
+	rim, err := mesh.NewModel(sweepProfile(seenFromBelow, []vector.Vector2{
+		vector.NewVector2(bottomOfSide.X(), bottom),
+		vector.NewVector2(-reverse.border, bottom),
+		vector.NewVector2(-reverse.border, bottom-reverse.impression),
+	}))
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, err
+	}
 
-	defer timeTrack(time.Now(), fmt.Sprintf("Generating Text: %s", textToWrite))
+	facePolys, err := reverse.face(seenFromBelow(-reverse.border), bottom-reverse.impression)
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, err
+	}
+	face, err := mesh.NewModel(facePolys)
+	if err != nil {
+		return mesh.Model{}, mesh.Model{}, err
+	}
 
-	fontByteData, err := ioutil.ReadFile("./sample.ttf")
+	return turnOver(face), turnOver(rim), nil
+}
 
-	if err != nil {
-		return nil, err
+// turnOver spins the model half way around the Z axis, the way a medal is
+// turned over side to side to look at its back. What faced up faces down,
+// and what read left to right from above reads left to right from below.
+func turnOver(m mesh.Model) mesh.Model {
+	return m.Scale(vector.NewVector3(-1, -1, 1), vector.Vector3Zero())
+}
+
+// GlyphShape is a single letter of text laid out by TextToShape, which is
+// a whole grapheme cluster so accents stay with the letters they're on.
+type GlyphShape struct {
+	Text string
+
+	// Shapes are the contours of the glyph, already moved to its origin.
+	// Whitespace has none.
+	Shapes []mesh.Shape
+
+	// Origin is where the glyph sits on the baseline
+	Origin vector.Vector2
+
+	// Advance is how far along the baseline the glyph takes up before the
+	// next one starts, not counting any kerning between them
+	Advance float64
+}
+
+// TextShape is a line of text laid out by TextToShape.
+type TextShape struct {
+	// Glyphs are in the order they're drawn from left to right, which is
+	// the reverse of the order right to left text is written in
+	Glyphs []GlyphShape
+
+	// Width is how far it is from the origin of the first glyph to the end
+	// of the last one's advance
+	Width float64
+
+	// Missing is every character neither the font nor its fallbacks have a
+	// glyph for, which are drawn with the font's missing glyph instead
+	Missing []rune
+}
+
+// Shapes is every contour of every glyph in the text.
+func (t TextShape) Shapes() []mesh.Shape {
+	shapes := make([]mesh.Shape, 0)
+	for _, glyph := range t.Glyphs {
+		shapes = append(shapes, glyph.Shapes...)
 	}
+	return shapes
+}
+
+// TextToShape builds the contours of every letter in the text, placing each
+// letter after the last by the font's advance widths and kerning. Text is
+// normalized, shaped and put in reading order first, so names in any script
+// come out the way they're written. Characters the font has no glyph for are
+// drawn from the first of the fallback fonts that has one. Curves in the
+// glyphs are flattened so they never stray further than curveTolerance from
+// the real curve. Contours keep the font's winding so holes can be told apart
+// from the shells they sit in.
+func TextToShape(textToWrite string, parsedFont *truetype.Font, curveTolerance float64, fallbacks ...*truetype.Font) (TextShape, error) {
 
-	parsedFont, err := truetype.Parse(fontByteData)
+	defer timeTrack(time.Now(), fmt.Sprintf("Generating Text: %s", textToWrite))
 
+	shape, err := shapeText(textToWrite, parsedFont, curveTolerance, fallbacks...)
 	if err != nil {
-		return nil, err
+		return TextShape{}, err
 	}
+	logMissingGlyphs(shape.Missing, parsedFont, fallbacks)
+	return shape, nil
+}
 
-	finalWord := make([][]mesh.Shape, len(textToWrite))
+// shapeText does the work of TextToShape without logging, for laying out
+// text a piece at a time.
+func shapeText(textToWrite string, parsedFont *truetype.Font, curveTolerance float64, fallbacks ...*truetype.Font) (TextShape, error) {
+	if parsedFont == nil {
+		return TextShape{}, errors.New("Need a font to write text with")
+	}
+	fonts := append([]*truetype.Font{parsedFont}, fallbacks...)
+	for _, fallback := range fallbacks {
+		if fallback == nil {
+			return TextShape{}, errors.New("Fallback fonts can't be empty")
+		}
+	}
 
-	accumulatedWidth := 0.
+	hasGlyph := func(r rune) bool {
+		_, ok := glyphFont(fonts, r)
+		return ok
+	}
+	clusters := visualOrder(shapeArabic(textClusters(textToWrite), hasGlyph))
+	glyphs := make([]GlyphShape, len(clusters))
+	missing := make([]rune, 0)
+
+	pen := 0.
+	var previousFont *truetype.Font
+	var previous truetype.Index
+	for i, cluster := range clusters {
+		start := pen
+		placed := false
+		contours := make([]mesh.Shape, 0)
+
+		// Marks with no width of their own are drawn over the letter
+		// before them
+		for _, char := range cluster {
+			charFont, ok := glyphFont(fonts, char)
+			if !ok {
+				// Joiners and other invisible characters aren't missed
+				if unicode.Is(unicode.Cf, char) || unicode.IsControl(char) {
+					continue
+				}
+				if !containsRune(missing, char) {
+					missing = append(missing, char)
+				}
+			}
+			index := charFont.Index(char)
 
-	for charIndex, char := range textToWrite {
+			// Only letters drawn from the same font can be kerned together
+			if previousFont == charFont {
+				pen += fontUnits(charFont.Kern(glyphLoadScale, previous, index))
+			}
+			previousFont = charFont
+			previous = index
 
-		glyph := truetype.GlyphBuf{}
-		glyph.Load(parsedFont, 100, parsedFont.Index(char), font.HintingNone)
+			// Kerning with the letter before moves the whole cluster
+			if !placed {
+				start = pen
+				placed = true
+			}
 
-		letterPoints := make([]vector.Vector2, len(glyph.Points))
-		for i, p := range glyph.Points {
-			letterPoints[i] = vector.NewVector2(float64(p.X), float64(p.Y))
+			shapes, err := glyphShapes(charFont, char, curveTolerance)
+			if err != nil {
+				return TextShape{}, err
+			}
+			for _, shape := range shapes {
+				contours = append(contours, shape.Translate(vector.NewVector2(pen, 0)))
+			}
+			pen += fontUnits(charFont.HMetric(glyphLoadScale, index).AdvanceWidth)
 		}
 
-		shape, err := mesh.NewShape(letterPoints)
-		if err != nil {
-			continue
+		glyphs[i] = GlyphShape{
+			Text:    cluster,
+			Shapes:  contours,
+			Origin:  vector.NewVector2(start, 0),
+			Advance: pen - start,
 		}
+	}
 
-		shrunkShape := shape.Scale(.01)
-
-		bottomLeftBounds, topRightBounds := shrunkShape.GetBounds()
-		accumulatedWidth += (topRightBounds.X() - bottomLeftBounds.X())
+	return TextShape{Glyphs: glyphs, Width: pen, Missing: missing}, nil
+}
 
-		finalWord[charIndex] = []mesh.Shape{shrunkShape.Translate(vector.NewVector2(accumulatedWidth, 0))}
+// logMissingGlyphs reports the characters neither the font nor its
+// fallbacks could draw.
+func logMissingGlyphs(missing []rune, parsedFont *truetype.Font, fallbacks []*truetype.Font) {
+	if len(missing) == 0 {
+		return
+	}
+	fontName := parsedFont.Name(truetype.NameIDFontFullName)
+	if len(fallbacks) > 0 {
+		fontName += " and its fallbacks"
 	}
+	log.Printf("%s have no glyph for %q, drawing the missing glyph instead", fontName, string(missing))
+}
 
-	return finalWord, nil
+func containsRune(runes []rune, r rune) bool {
+	for _, existing := range runes {
+		if existing == r {
+			return true
+		}
+	}
+	return false
 }
 
-func saveMedal(medal mesh.Model, name string) error {
+func saveMedal(parts []medalPart, output outputSpec) error {
 	defer timeTrack(time.Now(), "Saving Medal")
 
-	f, err := os.Create(name)
+	// glTF bakes materials into the file rather than referencing them, with
+	// the library found next to the output the same as an OBJ viewer would
+	var library map[string]*mtlMaterial
+	if (output.Format == "gltf" || output.Format == "glb") && output.MaterialLibrary != "" {
+		var err error
+		library, err = loadMTL(filepath.Join(filepath.Dir(output.Path), output.MaterialLibrary))
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(output.Path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
 	w := bufio.NewWriter(f)
-	err = medal.Save(w)
+	switch output.Format {
+	case "obj":
+		err = writeOBJ(w, objLibraries(parts, filepath.Dir(output.Path)), output.MaterialLibrary)
+	case "stl":
+		err = writeBinarySTL(w, parts, output.Units)
+	case "stl-ascii":
+		err = writeASCIISTL(w, parts, output.Units)
+	case "gltf":
+		err = writeGLTF(w, parts, library, output.Units)
+	case "glb":
+		err = writeGLB(w, parts, library, output.Units)
+	default:
+		err = fmt.Errorf("unsupported format %q", output.Format)
+	}
 	if err != nil {
 		return err
 	}
 	return w.Flush()
 }
 
-func ExtrudeShape(shapes []mesh.Shape, dist float64) (mesh.Model, error) {
+// objLibraries points the material libraries parts came with at where they
+// are from the directory an OBJ is saved to, since OBJ viewers look for
+// libraries next to the OBJ.
+func objLibraries(parts []medalPart, dir string) []medalPart {
+	moved := make([]medalPart, len(parts))
+	for i, part := range parts {
+		moved[i] = part
+		if part.materialLibrary == "" {
+			continue
+		}
+		library, err := filepath.Abs(part.materialLibrary)
+		if err != nil {
+			continue
+		}
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if relative, err := filepath.Rel(absDir, library); err == nil {
+				library = relative
+			}
+		}
+		moved[i].materialLibrary = filepath.ToSlash(library)
+	}
+	return moved
+}
+
+// flipWinding reverses the order of every face's vertices, turning the
+// model inside out. Needed after mirroring a model.
+func flipWinding(m mesh.Model) (mesh.Model, error) {
+	flipped := make([]mesh.Polygon, len(m.GetFaces()))
+	for i, face := range m.GetFaces() {
+		vertices := face.GetVertices()
+		reversed := make([]vector.Vector3, len(vertices))
+		for v := range vertices {
+			reversed[len(vertices)-1-v] = vertices[v]
+		}
 
-	polys, err := fill(shapes)
-	if err != nil {
-		return mesh.Model{}, err
+		var err error
+		uvs := face.GetUVs()
+		if len(uvs) == len(vertices) {
+			reversedUVs := make([]vector.Vector2, len(uvs))
+			for v := range uvs {
+				reversedUVs[len(uvs)-1-v] = uvs[v]
+			}
+			flipped[i], err = mesh.NewPolygonWithTexture(reversed, reversed, reversedUVs)
+		} else {
+			flipped[i], err = mesh.NewPolygon(reversed, reversed)
+		}
+		if err != nil {
+			return mesh.Model{}, err
+		}
 	}
+	return mesh.NewModel(flipped)
+}
+
+// orientUp winds every face so it points up once laid on the XZ plane,
+// which is when it's wound clockwise in 2D.
+func orientUp(v [][2]float64, faces [][3]int32) [][3]int32 {
+	oriented := make([][3]int32, len(faces))
+	for i, face := range faces {
+		a, b, c := v[face[0]], v[face[1]], v[face[2]]
+		cross := ((b[0] - a[0]) * (c[1] - a[1])) - ((b[1] - a[1]) * (c[0] - a[0]))
+		if cross > 0 {
+			face[1], face[2] = face[2], face[1]
+		}
+		oriented[i] = face
+	}
+	return oriented
+}
 
-	model, err := mesh.NewModel(polys)
+// capBoundary finds every edge of the triangulated cap that only belongs to
+// a single face. Faces are expected to share the same winding, so every edge
+// comes back in the direction it runs along its face.
+func capBoundary(faces [][3]int32) [][2]int32 {
+	type edge struct{ a, b int32 }
+
+	uses := make(map[edge]int)
+	directed := make([]edge, 0, len(faces)*3)
+	for _, face := range faces {
+		for i := 0; i < 3; i++ {
+			e := edge{face[i], face[(i+1)%3]}
+			directed = append(directed, e)
+			if e.a > e.b {
+				e = edge{e.b, e.a}
+			}
+			uses[e]++
+		}
+	}
+
+	boundary := make([][2]int32, 0)
+	for _, e := range directed {
+		key := e
+		if key.a > key.b {
+			key = edge{key.b, key.a}
+		}
+		if uses[key] == 1 {
+			boundary = append(boundary, [2]int32{e.a, e.b})
+		}
+	}
+	return boundary
+}
+
+// ExtrudeShape fills in the outlines and pulls them up by dist into a closed
+// solid. The top cap faces up, the bottom cap faces down, and walls are only
+// built along the edges of the cap that border empty space, all facing out.
+func ExtrudeShape(outlines []Outline, dist float64) (mesh.Model, error) {
+
+	v, faces, err := triangulateOutlines(outlines)
 	if err != nil {
 		return mesh.Model{}, err
 	}
 
-	otherEnd := model.Translate(vector.NewVector3(0, dist, 0))
+	return extrudeTriangulation(v, faces, dist)
+}
 
-	stitching := make([]mesh.Polygon, 0)
-	for polyIndex := 0; polyIndex < len(polys); polyIndex++ {
+// extrudeTriangulation pulls a triangulated cap laying on the XZ plane up by
+// dist into a closed solid.
+func extrudeTriangulation(v [][2]float64, faces [][3]int32, dist float64) (mesh.Model, error) {
+	bottom := func(i int32) vector.Vector3 { return vector.NewVector3(v[i][0], 0, v[i][1]) }
+	top := func(i int32) vector.Vector3 { return vector.NewVector3(v[i][0], dist, v[i][1]) }
 
-		verticesStart := polys[polyIndex].GetVertices()
-		verticesEnd := otherEnd.GetFaces()[polyIndex].GetVertices()
+	oriented := orientUp(v, faces)
 
-		for v := 0; v < len(verticesStart); v++ {
-			endingIndex := (v + 1) % len(verticesStart)
-			stitching = append(stitching, makeSquareWithTexture(
-				verticesStart[v],
-				verticesEnd[v],
-				verticesEnd[endingIndex],
-				verticesStart[endingIndex],
-				vector.NewVector2(0, 0),
-				vector.NewVector2(0, 1),
-				vector.NewVector2(1, 1),
-				vector.NewVector2(1, 0),
-			)...)
+	polys := make([]mesh.Polygon, 0, len(oriented)*2)
+	for _, face := range oriented {
+		topVerts := []vector.Vector3{top(face[0]), top(face[1]), top(face[2])}
+		poly, err := mesh.NewPolygon(topVerts, topVerts)
+		if err != nil {
+			return mesh.Model{}, err
 		}
+		polys = append(polys, poly)
 
+		bottomVerts := []vector.Vector3{bottom(face[2]), bottom(face[1]), bottom(face[0])}
+		poly, err = mesh.NewPolygon(bottomVerts, bottomVerts)
+		if err != nil {
+			return mesh.Model{}, err
+		}
+		polys = append(polys, poly)
 	}
 
-	stiches, err := mesh.NewModel(stitching)
-
-	if err != nil {
-		return mesh.Model{}, err
+	for _, edge := range capBoundary(oriented) {
+		polys = append(polys, makeSquareWithTexture(
+			bottom(edge[1]),
+			top(edge[1]),
+			top(edge[0]),
+			bottom(edge[0]),
+			vector.NewVector2(0, 0),
+			vector.NewVector2(0, 1),
+			vector.NewVector2(1, 1),
+			vector.NewVector2(1, 0),
+		)...)
 	}
 
-	return model.Merge(otherEnd).Merge(stiches), nil
+	return mesh.NewModel(polys)
 }
 
-func TextToModel(text string, scale, extrusion float64, letterShapeModifier func([][]mesh.Shape) []mesh.Shape) (mesh.Model, error) {
-	letterShapes, err := TextToShape(text)
+// TextToOutlines lays out the text and turns it into outlines ready to be
+// placed on the face of a medal. Text is scaled about its center and
+// mirrored so it reads correctly once laid on the XZ plane, and ends up
+// centered horizontally.
+func TextToOutlines(text string, parsedFont *truetype.Font, curveTolerance, scale float64, letterShapeModifier func(TextShape) []mesh.Shape, fallbacks ...*truetype.Font) ([]Outline, error) {
+	letterShapes, err := TextToShape(text, parsedFont, curveTolerance, fallbacks...)
 	if err != nil {
-		return mesh.Model{}, err
+		return nil, err
+	}
+
+	outlines := classifyContours(letterShapeModifier(letterShapes))
+	if len(outlines) == 0 {
+		return nil, nil
 	}
 
-	model, err := ExtrudeShape(letterShapeModifier(letterShapes), extrusion)
+	center := outlinesCenter(outlines)
+	for i, outline := range outlines {
+		outlines[i] = outline.
+			Translate(center.MultByConstant(-1)).
+			MirrorX().
+			Scale(scale).
+			Translate(vector.NewVector2(0, center.Y()))
+	}
+
+	return outlines, nil
+}
+
+// TextToModel extrudes the text up by extrusion.
+func TextToModel(text string, parsedFont *truetype.Font, curveTolerance, scale, extrusion float64, letterShapeModifier func(TextShape) []mesh.Shape, fallbacks ...*truetype.Font) (mesh.Model, error) {
+	outlines, err := TextToOutlines(text, parsedFont, curveTolerance, scale, letterShapeModifier, fallbacks...)
 	if err != nil {
 		return mesh.Model{}, err
 	}
 
-	return model.Scale(vector.NewVector3(-scale, scale, scale), model.GetCenterOfBoundingBox()), nil
+	return ExtrudeShape(outlines, extrusion)
 }
 
 func timeTrack(start time.Time, name string) {
@@ -436,117 +797,384 @@ func timeTrack(start time.Time, name string) {
 	log.Printf("%s took %s", name, elapsed)
 }
 
-func main() {
+// placeLogoModel scales the logo evenly by its scale, turns it onto the
+// medal's face and moves its center to its offset, height above the bottom.
+func placeLogoModel(logoMesh mesh.Model, logo logoSpec) mesh.Model {
+	smallerLogo := logoMesh.
+		Scale(
+			vector.Vector3One().MultByConstant(logo.Scale),
+			logoMesh.GetCenterOfBoundingBox(),
+		)
 
-	startingRadius := 1.0
+	smallerLogo = Rotate(
+		smallerLogo,
+		smallerLogo.GetCenterOfBoundingBox(),
+		mesh.NewQuaternion(vector.Vector3Right(), logoTilt),
+	)
+
+	// Turning around Y is counter clockwise looking down at the face, the
+	// same way SVG logos turn
+	smallerLogo = Rotate(
+		smallerLogo,
+		smallerLogo.GetCenterOfBoundingBox(),
+		mesh.NewQuaternion(vector.Vector3Up(), logo.Rotation*math.Pi/180),
+	)
 
-	medallionThickness := 0.3
+	return smallerLogo.
+		Translate(vector.NewVector3(logo.Offset[0], logo.Height, logo.Offset[1]).Sub(smallerLogo.GetCenterOfBoundingBox()))
+}
 
-	medallionImpression := 0.1
+// logoTilt is how far mesh logos are tipped around X, in radians, as they're
+// stood on the face.
+const logoTilt = -1.
 
-	medal, err := MakeMedalion(startingRadius, medallionThickness, medallionImpression)
+// placeLogo moves every part of the logo onto the face of the medal as a
+// whole, keeping each part's name and material.
+func placeLogo(parts []medalPart, logo logoSpec) ([]medalPart, error) {
+	faces := make([]mesh.Polygon, 0)
+	for _, part := range parts {
+		faces = append(faces, part.model.GetFaces()...)
+	}
 
+	whole, err := mesh.NewModel(faces)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	placedFaces := placeLogoModel(whole, logo).GetFaces()
 
-	topText, err := TextToModel("Aleatha", .4, medallionImpression, func(letters [][]mesh.Shape) []mesh.Shape {
-		rotatedShapes := make([]mesh.Shape, 0)
-		angleIncrements := math.Pi / float64(len(letters))
+	placed := make([]medalPart, len(parts))
+	start := 0
+	for i, part := range parts {
+		end := start + len(part.model.GetFaces())
+		model, err := mesh.NewModel(placedFaces[start:end])
+		if err != nil {
+			return nil, err
+		}
+		start = end
 
-		for i, letterShape := range letters {
-			curAngle := (math.Pi / 2.0) - (angleIncrements * float64(i)) - (angleIncrements / 2.0)
-			centerOfShapes := mesh.CenterOfBoundingBoxOfShapes(letterShape)
-			for _, shape := range letterShape {
-				repositioned := shape.
-					Scale(.75).
-					Translate(centerOfShapes.MultByConstant(-1).Add(vector.NewVector2(0.0, startingRadius*2)))
-				rotatedShapes = append(rotatedShapes, repositioned.Rotate(curAngle, vector.Vector2Zero()))
-			}
+		placed[i] = medalPart{name: part.name, material: part.material, materialLibrary: part.materialLibrary, model: model}
+		if logo.Material != "" {
+			placed[i].material = logo.Material
+			placed[i].materialLibrary = ""
 		}
-		return rotatedShapes
-	})
+	}
+	return placed, nil
+}
+
+// placeEmblem centers the outlines of an SVG logo on the face, scaling them
+// so their longest side is logo.Scale long before turning and moving them.
+// Looking down at the face, X points left and Y points up, so SVG
+// coordinates are spun half way around to read the same as the SVG does.
+func placeEmblem(outlines []Outline, logo logoSpec) []Outline {
+	scale := emblemScale(outlines, logo)
+
+	// Rotations are counter clockwise looking down at the face, which is
+	// clockwise with X pointing left
+	angle := -logo.Rotation * math.Pi / 180.0
+
+	center := outlinesCenter(outlines)
+	placed := make([]Outline, len(outlines))
+	for i, outline := range outlines {
+		placed[i] = outline.
+			Translate(center.MultByConstant(-1)).
+			Scale(-scale).
+			Rotate(angle, vector.Vector2Zero()).
+			Translate(vector.NewVector2(logo.Offset[0], logo.Offset[1]))
+	}
+	return placed
+}
 
+// emblemScale is how much placeEmblem scales the outlines by to make their
+// longest side as long as the logo's scale.
+func emblemScale(outlines []Outline, logo logoSpec) float64 {
+	bottomLeft, topRight := outlinesBounds(outlines)
+	size := topRight.Sub(bottomLeft)
+	return logo.Scale / math.Max(size.X(), size.Y())
+}
+
+// flattenedEmblem reads the SVG at path with its curves as fine as the
+// quality needs once scaleOf has sized it for the medal. Sizing it takes its
+// shapes, so it's read roughly to measure them first.
+func flattenedEmblem(path string, quality qualitySpec, scaleOf func([]Outline) (float64, error), assets *medalAssets) ([]Outline, error) {
+	rough, err := assets.emblem(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := scaleOf(rough)
+	if err != nil {
+		return nil, err
+	}
+	return assets.emblem(path, quality.Tolerance/scale)
+}
+
+// checkLogoMaterials makes sure every material a logo's parts use without
+// bringing their own is in the output's material library, the same as
+// validate does for the rest of the medal.
+func checkLogoMaterials(parts []medalPart, logoPath string, output outputSpec) error {
+	if output.MaterialLibrary == "" {
+		return nil
+	}
+
+	libraryPath := filepath.Join(filepath.Dir(output.Path), output.MaterialLibrary)
+	library, err := loadMTL(libraryPath)
+	if os.IsNotExist(err) && output.Format == "obj" {
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	for _, part := range parts {
+		if _, ok := library[part.material]; part.material != "" && part.materialLibrary == "" && !ok {
+			return fmt.Errorf("material %q of logo %s isn't defined in %s or the logo's own material libraries", part.material, logoPath, libraryPath)
+		}
 	}
+	return nil
+}
 
-	bottomText, err := TextToModel(Reverse(" Singleton "), .4, medallionImpression, func(letters [][]mesh.Shape) []mesh.Shape {
-		rotatedShapes := make([]mesh.Shape, 0)
-		angleIncrements := math.Pi / float64(len(letters))
+// medalOutline is the outline of the medal's body in the shape the spec
+// asks for, with curves as fine as the quality needs all the way out to the
+// furthest the side sticks out. Custom shapes come out of assets so they're
+// only read once.
+func medalOutline(body bodySpec, quality qualitySpec, side []vector.Vector2, assets *medalAssets) (bodyOutline, error) {
+	furthest := 0.
+	for _, point := range side {
+		furthest = math.Max(furthest, point.X())
+	}
 
-		for i, letterShape := range letters {
-			curAngle := (math.Pi / 2.0) - (angleIncrements * float64(i)) - (angleIncrements / 2.0)
-			centerOfShapes := mesh.CenterOfBoundingBoxOfShapes(letterShape)
-			for _, shape := range letterShape {
-				repositioned := shape.
-					// Scale(.75).
-					Translate(centerOfShapes.MultByConstant(-1).Add(vector.NewVector2(0.0, -startingRadius*2)))
-				rotatedShapes = append(rotatedShapes, repositioned.Rotate(curAngle, vector.Vector2Zero()))
+	switch body.Shape {
+	case "circle":
+		return circleBody(quality.circleSides(body.Radius+furthest), body.Radius), nil
+	case "polygon":
+		return shapedBody(regularPolygonOutline(body.Sides, body.Radius)), nil
+	case "star":
+		return shapedBody(starOutline(body.Points, body.Radius, body.InnerRadius)), nil
+	case "shield":
+		width := body.Radius * shieldAspect * 2
+		height := body.Radius * 2
+
+		// The sides curve into the point across two thirds of the height
+		curve := math.Max(width/2, height*2/3) + furthest
+		return shapedBody(shieldOutline(width, height, quality.arcSegments(curve, math.Pi/2))), nil
+	case "custom":
+		shapes, err := flattenedEmblem(body.Path, quality, func(rough []Outline) (float64, error) {
+			_, _, scale, err := fitShape(rough, body.Radius)
+			if err != nil {
+				return 0, fmt.Errorf("unable to use %s as the medal's shape: %w", body.Path, err)
 			}
+			return scale, nil
+		}, assets)
+		if err != nil {
+			return nil, err
 		}
-		return rotatedShapes
-	})
+		outline, err := fitOutline(shapes, body.Radius)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use %s as the medal's shape: %w", body.Path, err)
+		}
+		return shapedBody(outline), nil
+	}
+	return nil, fmt.Errorf("unsupported shape %q", body.Shape)
+}
 
-	if err != nil {
-		panic(err)
+// buildDesign lays out the text, SVG logo and relief on one face of the
+// medal, which sits faceHeight up and fits text inside faceRadius. SVG logos
+// are flattened as finely as the quality needs. Anything raised out of the
+// face comes back as parts named with the prefix, and anything cut into it or
+// raised out of the face itself is made by the returned face.
+func buildDesign(texts []textSpec, logo *logoSpec, reliefImage *reliefSpec, faceRadius, faceHeight float64, quality qualitySpec, prefix string, assets *medalAssets) ([]medalPart, medalFace, error) {
+	designParts := make([]medalPart, 0)
+	engravings := make([]engraving, 0)
+	for i, text := range texts {
+		textFont, err := assets.font(text.Font)
+		if err != nil {
+			return nil, nil, err
+		}
+		fallbacks := make([]*truetype.Font, len(text.FallbackFonts))
+		for f, path := range text.FallbackFonts {
+			if fallbacks[f], err = assets.font(path); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		outlines, err := textLayouts[text.Layout].layout(text, textFont, fallbacks, faceRadius)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if text.Style == "engrave" {
+			engravings = append(engravings, engraving{outlines: outlines, depth: text.Depth})
+			continue
+		}
+
+		textModel, err := ExtrudeShape(outlines, text.Depth)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		designParts = append(designParts, medalPart{
+			name:     fmt.Sprintf("%stext_%d", prefix, i),
+			material: text.Material,
+			model:    textModel.Translate(vector.NewVector3(0, faceHeight, 0)),
+		})
 	}
 
-	topTextCentered := topText.Translate(vector.NewVector3(
-		-topText.GetCenterOfBoundingBox().X(),
-		medallionThickness-medallionImpression,
-		-.75,
-	))
+	// SVG logos are cut into the face like engraved text, so they need to be
+	// known before the face is made
+	if logo != nil && logo.isSVG() {
+		emblem, err := flattenedEmblem(logo.Path, quality, func(rough []Outline) (float64, error) {
+			return emblemScale(rough, *logo), nil
+		}, assets)
+		if err != nil {
+			return nil, nil, err
+		}
+		outlines := placeEmblem(emblem, *logo)
+
+		if logo.Style == "engrave" {
+			engravings = append(engravings, engraving{outlines: outlines, depth: logo.Depth})
+		} else {
+			logoModel, err := ExtrudeShape(outlines, logo.Depth)
+			if err != nil {
+				return nil, nil, err
+			}
+			designParts = append(designParts, medalPart{
+				name:     prefix + "logo",
+				material: logo.Material,
+				model:    logoModel.Translate(vector.NewVector3(0, faceHeight, 0)),
+			})
+		}
+	}
 
-	bottomTextCentered := bottomText.Translate(vector.NewVector3(
-		-bottomText.GetCenterOfBoundingBox().X(),
-		medallionThickness-medallionImpression,
-		1,
-	))
+	designFace := engravedFace(engravings)
+	if reliefImage != nil {
+		heights, err := assets.smoothHeightmap(reliefImage.Path, reliefImage.Smoothing)
+		if err != nil {
+			return nil, nil, err
+		}
+		designFace = reliefFace(relief{
+			heights:      heights,
+			height:       reliefImage.Height,
+			invert:       reliefImage.Invert,
+			maxTriangles: reliefImage.MaxTriangles,
+		})
+	}
 
-	logoReader, err := os.Open("its_logo.stl.obj")
-	if err != nil {
-		panic(err)
+	return designParts, designFace, nil
+}
+
+// buildMedal creates the medal body along with all text and logos that sit
+// on top of it, each as their own part so they can carry their own material.
+// Fonts and logos come out of assets so they're only read once.
+func buildMedal(spec medalSpec, assets *medalAssets) ([]medalPart, error) {
+	if err := spec.validate(nil); err != nil {
+		return nil, err
 	}
 
-	logoMesh, err := importOBJ(logoReader)
+	body := spec.Body
+	faceHeight := body.Thickness - body.Impression
+
+	edge := spec.edge()
+	side := edgeProfiles[edge.Profile].points(edge, body.Thickness)
+	outline, err := medalOutline(body, spec.Quality, side, assets)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	smallerLogo := logoMesh.
-		Scale(
-			vector.Vector3One().MultByConstant(1.0/30.0),
-			logoMesh.GetCenterOfBoundingBox(),
-		)
+	// Nothing stops a custom shape from being too narrow for its rims
+	if body.Shape == "custom" {
+		borders := []float64{body.Rim.Border}
+		if spec.Reverse != nil {
+			borders = append(borders, spec.Reverse.Rim.Border)
+		}
+		for _, border := range borders {
+			if err := checkBorder(outline, border); err != nil {
+				return nil, fmt.Errorf("unable to use %s as the medal's shape: %w", body.Path, err)
+			}
+		}
+	}
 
-	smallerLogo = Rotate(
-		smallerLogo,
-		smallerLogo.GetCenterOfBoundingBox(),
-		mesh.NewQuaternion(vector.Vector3Right(), -math.Sin(math.Pi/2)),
-	)
+	// Text is fit inside the largest circle that fits inside the face
+	faceRadius := body.Radius - body.Rim.Border
+	if body.Shape != "circle" {
+		faceRadius = inscribedRadius(outline(-body.Rim.Border))
+	}
 
-	smallerLogo = Rotate(
-		smallerLogo,
-		smallerLogo.GetCenterOfBoundingBox(),
-		mesh.NewQuaternion(vector.Vector3Up(), -math.Sin(math.Pi)),
-	)
+	designParts, designFace, err := buildDesign(spec.Text, spec.Logo, spec.Relief, faceRadius, faceHeight, spec.Quality, "", assets)
+	if err != nil {
+		return nil, err
+	}
 
-	smallerLogo = smallerLogo.
-		Translate(vector.NewVector3(0, .2, 0).Sub(smallerLogo.GetCenterOfBoundingBox()))
+	// The reverse is designed on a face of its own, as if it were the front,
+	// and turned over with the rest of the reverse
+	var reverse *medalReverse
+	reverseParts := make([]medalPart, 0)
+	if spec.Reverse != nil {
+		reverseRadius := body.Radius - spec.Reverse.Rim.Border
+		if body.Shape != "circle" {
+			reverseRadius = inscribedRadius(outline(-spec.Reverse.Rim.Border))
+		}
 
-	smallerLogo = smallerLogo.
-		Scale(
-			vector.NewVector3(1.0/3.0, 1, 1.0/3.0),
-			smallerLogo.GetCenterOfBoundingBox(),
-		).
-		Translate(vector.NewVector3(0, .02, 0))
+		parts, face, err := buildDesign(spec.Reverse.Text, spec.Reverse.Logo, spec.Reverse.Relief, reverseRadius, -spec.Reverse.Impression, spec.Quality, "reverse_", assets)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			part.model = turnOver(part.model)
+			reverseParts = append(reverseParts, part)
+		}
 
-	// err = saveMedal(medal.Merge(topTextCentered), "out.obj")
-	err = saveMedal(medal.Merge(smallerLogo).Merge(topTextCentered).Merge(bottomTextCentered), "out.obj")
+		reverse = &medalReverse{
+			impression: spec.Reverse.Impression,
+			border:     spec.Reverse.Rim.Border,
+			face:       face,
+		}
+	}
 
+	medal, rim, reverseRim, err := makeMedalionParts(outline, side, body.Impression, body.Rim.Border, designFace, reverse)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	rimMaterial := body.Rim.Material
+	if rimMaterial == "" {
+		rimMaterial = body.Material
+	}
+
+	parts := []medalPart{
+		{name: "body", material: body.Material, model: medal},
+		{name: "rim", material: rimMaterial, model: rim},
+	}
+	parts = append(parts, designParts...)
+
+	if spec.Reverse != nil {
+		reverseRimMaterial := spec.Reverse.Rim.Material
+		if reverseRimMaterial == "" {
+			reverseRimMaterial = body.Material
+		}
+		parts = append(parts, medalPart{name: "reverse_rim", material: reverseRimMaterial, model: reverseRim})
+		parts = append(parts, reverseParts...)
+	}
+
+	if spec.Logo != nil && !spec.Logo.isSVG() {
+		logoParts, err := assets.logo(spec.Logo.Path)
+		if err != nil {
+			return nil, err
+		}
+		placed, err := placeLogo(logoParts, *spec.Logo)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLogoMaterials(placed, spec.Logo.Path, spec.Output); err != nil {
+			return nil, err
+		}
+		parts = append(parts, placed...)
 	}
 
+	return parts, nil
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
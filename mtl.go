@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mtlMaterial is a single material read from a Wavefront MTL file.
+type mtlMaterial struct {
+	Name string
+
+	// Ambient color, Ka
+	Ambient [3]float64
+
+	// Diffuse color, Kd
+	Diffuse [3]float64
+
+	// Specular color, Ks
+	Specular [3]float64
+
+	// Shininess is the specular exponent, Ns
+	Shininess float64
+
+	// OpticalDensity is the index of refraction, Ni
+	OpticalDensity float64
+
+	// Dissolve is how opaque the material is, d, or 1 - Tr
+	Dissolve float64
+
+	// Illumination is which lighting model to use, illum
+	Illumination int
+
+	// Texture maps, with paths relative to the MTL file until loaded with
+	// loadMTL
+	AmbientTexture   string // map_Ka
+	DiffuseTexture   string // map_Kd
+	SpecularTexture  string // map_Ks
+	ShininessTexture string // map_Ns
+	DissolveTexture  string // map_d
+	BumpTexture      string // map_bump or bump
+}
+
+// textures points at every texture path of the material, keyed by the
+// statement that sets it.
+func (m *mtlMaterial) textures() map[string]*string {
+	return map[string]*string{
+		"map_Ka":   &m.AmbientTexture,
+		"map_Kd":   &m.DiffuseTexture,
+		"map_Ks":   &m.SpecularTexture,
+		"map_Ns":   &m.ShininessTexture,
+		"map_d":    &m.DissolveTexture,
+		"map_bump": &m.BumpTexture,
+		"bump":     &m.BumpTexture,
+	}
+}
+
+// mtlMapOptions is how many values follow each option that can come before
+// the path of a texture map. Options taking up to 3 values list the most.
+var mtlMapOptions = map[string]int{
+	"-blendu":  1,
+	"-blendv":  1,
+	"-bm":      1,
+	"-boost":   1,
+	"-cc":      1,
+	"-clamp":   1,
+	"-imfchan": 1,
+	"-mm":      2,
+	"-o":       3,
+	"-s":       3,
+	"-t":       3,
+	"-texres":  1,
+}
+
+// mtlMapPath skips over any options of a texture map statement to find the
+// path of the texture, which is allowed to contain spaces.
+func mtlMapPath(fields []string) (string, error) {
+	i := 0
+	for i < len(fields) {
+		values, ok := mtlMapOptions[fields[i]]
+		if !ok {
+			break
+		}
+		i++
+
+		// Options with up to 3 values stop at the first thing that isn't a
+		// number
+		for taken := 0; taken < values && i < len(fields); taken++ {
+			if _, err := strconv.ParseFloat(fields[i], 64); err != nil && taken > 0 {
+				break
+			}
+			i++
+		}
+	}
+
+	if i >= len(fields) {
+		return "", fmt.Errorf("requires a path")
+	}
+	return strings.Join(fields[i:], " "), nil
+}
+
+func parseMTLFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+	}
+	values := make([]float64, count)
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %q as a number", field)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// parseMTLColor reads an RGB color, where a single value is used for all
+// three channels.
+func parseMTLColor(fields []string) ([3]float64, error) {
+	color := [3]float64{}
+	if len(fields) == 1 {
+		values, err := parseMTLFloats(fields, 1)
+		if err != nil {
+			return color, err
+		}
+		return [3]float64{values[0], values[0], values[0]}, nil
+	}
+
+	values, err := parseMTLFloats(fields, 3)
+	if err != nil {
+		return color, err
+	}
+	copy(color[:], values)
+	return color, nil
+}
+
+// readMTL reads every material out of an MTL file. Texture paths are left
+// as they're written in the file.
+func readMTL(in io.Reader) (map[string]*mtlMaterial, error) {
+	materials := make(map[string]*mtlMaterial)
+	var current *mtlMaterial
+
+	scanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		text := scanner.Text()
+		if comment := strings.Index(text, "#"); comment != -1 {
+			text = text[:comment]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: newmtl requires a name", lineNumber)
+			}
+			current = &mtlMaterial{Name: fields[1], Dissolve: 1, OpticalDensity: 1}
+			materials[current.Name] = current
+			continue
+		}
+
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %s found before any newmtl", lineNumber, fields[0])
+		}
+
+		var err error
+		var values []float64
+		switch fields[0] {
+		case "Ka":
+			current.Ambient, err = parseMTLColor(fields[1:])
+		case "Kd":
+			current.Diffuse, err = parseMTLColor(fields[1:])
+		case "Ks":
+			current.Specular, err = parseMTLColor(fields[1:])
+
+		case "Ns":
+			if values, err = parseMTLFloats(fields[1:], 1); err == nil {
+				current.Shininess = values[0]
+			}
+		case "Ni":
+			if values, err = parseMTLFloats(fields[1:], 1); err == nil {
+				current.OpticalDensity = values[0]
+			}
+		case "d":
+			if values, err = parseMTLFloats(fields[1:], 1); err == nil {
+				current.Dissolve = values[0]
+			}
+		case "Tr":
+			if values, err = parseMTLFloats(fields[1:], 1); err == nil {
+				current.Dissolve = 1 - values[0]
+			}
+
+		case "illum":
+			if len(fields) != 2 {
+				err = fmt.Errorf("expected 1 value, got %d", len(fields)-1)
+			} else if current.Illumination, err = strconv.Atoi(fields[1]); err != nil {
+				err = fmt.Errorf("unable to parse %q as an illumination model", fields[1])
+			}
+
+		default:
+			if texture, ok := current.textures()[fields[0]]; ok {
+				*texture, err = mtlMapPath(fields[1:])
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNumber, fields[0], err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// loadMTL reads the MTL file at path, making texture paths relative to the
+// directory the file sits in.
+func loadMTL(path string) (map[string]*mtlMaterial, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	materials, err := readMTL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, material := range materials {
+		// bump and map_bump share the same texture, which only needs
+		// resolving once
+		resolved := make(map[*string]bool)
+		for _, texture := range material.textures() {
+			if resolved[texture] {
+				continue
+			}
+			resolved[texture] = true
+			if *texture != "" && !filepath.IsAbs(*texture) {
+				*texture = filepath.Join(filepath.Dir(path), *texture)
+			}
+		}
+	}
+	return materials, nil
+}
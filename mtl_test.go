@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMTL(t *testing.T) {
+	materials, err := loadMTL("master.mtl")
+	assert.NoError(t, err)
+
+	if assert.Contains(t, materials, "fresnel_win") {
+		fresnel := materials["fresnel_win"]
+		assert.Equal(t, [3]float64{0, 0, 1}, fresnel.Ambient)
+		assert.Equal(t, [3]float64{0, 0, 1}, fresnel.Diffuse)
+		assert.Equal(t, [3]float64{.618, .876, .143}, fresnel.Specular)
+		assert.Equal(t, 200., fresnel.Shininess)
+		assert.Equal(t, 1.2, fresnel.OpticalDensity)
+		assert.Equal(t, 7, fresnel.Illumination)
+		assert.Equal(t, 1., fresnel.Dissolve)
+	}
+
+	if assert.Contains(t, materials, "wood") {
+		assert.Equal(t, "wood.jpg", materials["wood"].AmbientTexture)
+		assert.Equal(t, "wood.jpg", materials["wood"].DiffuseTexture)
+	}
+}
+
+func TestReadMTLMapOptions(t *testing.T) {
+	materials, err := readMTL(strings.NewReader(`# Options come before the path
+newmtl plate
+Kd 0.5
+Tr 0.25
+map_Kd -s 2 2 1 -clamp on textures/brushed steel.png
+bump -bm 0.5 bump.png
+`))
+
+	assert.NoError(t, err)
+	if assert.Contains(t, materials, "plate") {
+		plate := materials["plate"]
+		assert.Equal(t, [3]float64{.5, .5, .5}, plate.Diffuse)
+		assert.Equal(t, .75, plate.Dissolve)
+		assert.Equal(t, "textures/brushed steel.png", plate.DiffuseTexture)
+		assert.Equal(t, "bump.png", plate.BumpTexture)
+	}
+}
+
+func TestReadMTLReportsLines(t *testing.T) {
+	_, err := readMTL(strings.NewReader("newmtl plate\nKd 1 0\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: Kd")
+	}
+
+	_, err = readMTL(strings.NewReader("Kd 1 1 1\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "before any newmtl")
+	}
+}
+
+func TestLoadMTLResolvesTexturesNextToLibrary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "medal.mtl")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("newmtl wood\nKd 0 0.8 0\nd 0.5\nmap_Kd textures/wood.jpg\nmap_bump bump.png\n"), 0644))
+
+	materials, err := loadMTL(path)
+	assert.NoError(t, err)
+	if assert.Contains(t, materials, "wood") {
+		assert.Equal(t, [3]float64{0, .8, 0}, materials["wood"].Diffuse)
+		assert.Equal(t, .5, materials["wood"].Dissolve)
+		assert.Equal(t, filepath.Join(dir, "textures", "wood.jpg"), materials["wood"].DiffuseTexture)
+		assert.Equal(t, filepath.Join(dir, "bump.png"), materials["wood"].BumpTexture)
+	}
+}
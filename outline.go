@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// Outline is a closed region made up of an outer contour and any number of
+// holes cut out of it. Outer contours wind counter clockwise and holes wind
+// clockwise.
+type Outline struct {
+	Outer mesh.Shape
+	Holes []mesh.Shape
+}
+
+// Shapes is every contour that makes up the outline, starting with the
+// outer contour.
+func (o Outline) Shapes() []mesh.Shape {
+	return append([]mesh.Shape{o.Outer}, o.Holes...)
+}
+
+// Translate moves every contour of the outline.
+func (o Outline) Translate(movement vector.Vector2) Outline {
+	holes := make([]mesh.Shape, len(o.Holes))
+	for i, hole := range o.Holes {
+		holes[i] = hole.Translate(movement)
+	}
+	return Outline{Outer: o.Outer.Translate(movement), Holes: holes}
+}
+
+// Scale grows or shrinks every contour of the outline about the origin.
+func (o Outline) Scale(amount float64) Outline {
+	holes := make([]mesh.Shape, len(o.Holes))
+	for i, hole := range o.Holes {
+		holes[i] = hole.Scale(amount)
+	}
+	return Outline{Outer: o.Outer.Scale(amount), Holes: holes}
+}
+
+// Rotate turns every contour of the outline around the pivot.
+func (o Outline) Rotate(amount float64, pivot vector.Vector2) Outline {
+	holes := make([]mesh.Shape, len(o.Holes))
+	for i, hole := range o.Holes {
+		holes[i] = hole.Rotate(amount, pivot)
+	}
+	return Outline{Outer: o.Outer.Rotate(amount, pivot), Holes: holes}
+}
+
+// signedArea is positive for counter clockwise contours and negative for
+// clockwise ones.
+func signedArea(points []vector.Vector2) float64 {
+	area := 0.
+	for i := range points {
+		next := points[(i+1)%len(points)]
+		area += (points[i].X() * next.Y()) - (next.X() * points[i].Y())
+	}
+	return area / 2.
+}
+
+func reversePoints(points []vector.Vector2) []vector.Vector2 {
+	reversed := make([]vector.Vector2, len(points))
+	for i, p := range points {
+		reversed[len(points)-1-i] = p
+	}
+	return reversed
+}
+
+// windShape returns the shape wound counter clockwise when ccw is true and
+// clockwise otherwise.
+func windShape(shape mesh.Shape, ccw bool) mesh.Shape {
+	points := shape.GetPoints()
+	if (signedArea(points) > 0) == ccw {
+		return shape
+	}
+	reversed, _ := mesh.NewShape(reversePoints(points))
+	return reversed
+}
+
+// pointInPolygon uses the even-odd rule to determine whether the point
+// falls inside the polygon.
+func pointInPolygon(point vector.Vector2, polygon []vector.Vector2) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a := polygon[i]
+		b := polygon[j]
+		if (a.Y() > point.Y()) != (b.Y() > point.Y()) {
+			crossing := (b.X()-a.X())*(point.Y()-a.Y())/(b.Y()-a.Y()) + a.X()
+			if point.X() < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// segmentsCross is whether the segment from a to b touches the segment from
+// c to d anywhere, including at their ends or by running along each other.
+func segmentsCross(a, b, c, d vector.Vector2) bool {
+	// side is positive when r is to the left of the line from p to q
+	side := func(p, q, r vector.Vector2) float64 {
+		return ((q.X() - p.X()) * (r.Y() - p.Y())) - ((q.Y() - p.Y()) * (r.X() - p.X()))
+	}
+	within := func(p, q, r vector.Vector2) bool {
+		return math.Min(p.X(), q.X()) <= r.X() && r.X() <= math.Max(p.X(), q.X()) &&
+			math.Min(p.Y(), q.Y()) <= r.Y() && r.Y() <= math.Max(p.Y(), q.Y())
+	}
+
+	d1, d2 := side(c, d, a), side(c, d, b)
+	d3, d4 := side(a, b, c), side(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	return (d1 == 0 && within(c, d, a)) ||
+		(d2 == 0 && within(c, d, b)) ||
+		(d3 == 0 && within(a, b, c)) ||
+		(d4 == 0 && within(a, b, d))
+}
+
+// crossesItself is whether any side of the closed outline touches another
+// side that isn't next to it.
+func crossesItself(outline []vector.Vector2) bool {
+	for i := range outline {
+		a, b := outline[i], outline[(i+1)%len(outline)]
+		for j := i + 2; j < len(outline); j++ {
+			// The last side joins back up with the first
+			if i == 0 && j == len(outline)-1 {
+				continue
+			}
+			if segmentsCross(a, b, outline[j], outline[(j+1)%len(outline)]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classifyContours groups contours into outlines using their winding. Every
+// contour wound the same way as the largest contour is an outer shell, and
+// every other contour is a hole belonging to the smallest shell around it.
+// Holes that don't sit in any shell are dropped.
+func classifyContours(contours []mesh.Shape) []Outline {
+	if len(contours) == 0 {
+		return nil
+	}
+
+	areas := make([]float64, len(contours))
+	largest := 0
+	for i, contour := range contours {
+		areas[i] = signedArea(contour.GetPoints())
+		if math.Abs(areas[i]) > math.Abs(areas[largest]) {
+			largest = i
+		}
+	}
+	outerIsCCW := areas[largest] > 0
+
+	shells := make([]int, 0)
+	holes := make([]int, 0)
+	for i := range contours {
+		if areas[i] == 0 {
+			continue
+		}
+		if (areas[i] > 0) == outerIsCCW {
+			shells = append(shells, i)
+		} else {
+			holes = append(holes, i)
+		}
+	}
+
+	// Smallest shells first so holes find the tightest shell around them
+	sort.Slice(shells, func(a, b int) bool {
+		return math.Abs(areas[shells[a]]) < math.Abs(areas[shells[b]])
+	})
+
+	outlines := make([]Outline, len(shells))
+	for i, shell := range shells {
+		outlines[i] = Outline{Outer: windShape(contours[shell], true)}
+	}
+
+	for _, hole := range holes {
+		holePoint := contours[hole].GetPoints()[0]
+		for i, shell := range shells {
+			if math.Abs(areas[shell]) > math.Abs(areas[hole]) && pointInPolygon(holePoint, contours[shell].GetPoints()) {
+				outlines[i].Holes = append(outlines[i].Holes, windShape(contours[hole], false))
+				break
+			}
+		}
+	}
+
+	return outlines
+}
+
+// interiorPoint finds a point strictly inside the region bounded by the
+// contours using the even-odd rule, so a point inside the first contour but
+// outside of any contour nested within it. It sweeps a few horizontal lines
+// across the contours and picks the middle of the widest span found.
+func interiorPoint(contours [][]vector.Vector2) (vector.Vector2, error) {
+	if len(contours) == 0 || len(contours[0]) < 3 {
+		return vector.Vector2{}, errors.New("Can't find a point inside a contour with less than 3 points")
+	}
+
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range contours[0] {
+		minY = math.Min(minY, p.Y())
+		maxY = math.Max(maxY, p.Y())
+	}
+
+	bestWidth := 0.
+	var best vector.Vector2
+
+	const sweeps = 16
+	for sweep := 0; sweep < sweeps; sweep++ {
+		// Start sweeping from the middle and work outwards, avoiding landing
+		// exactly on any vertex
+		offset := (float64((sweep+1)/2) / float64(sweeps+1)) * float64(1-2*(sweep%2))
+		y := minY + ((maxY - minY) * (.5 + offset + 1e-7))
+
+		crossings := make([]float64, 0)
+		for _, contour := range contours {
+			for i := range contour {
+				a := contour[i]
+				b := contour[(i+1)%len(contour)]
+				if (a.Y() > y) != (b.Y() > y) {
+					crossings = append(crossings, a.X()+(y-a.Y())*(b.X()-a.X())/(b.Y()-a.Y()))
+				}
+			}
+		}
+		sort.Float64s(crossings)
+
+		for i := 0; i+1 < len(crossings); i += 2 {
+			if width := crossings[i+1] - crossings[i]; width > bestWidth {
+				bestWidth = width
+				best = vector.NewVector2((crossings[i]+crossings[i+1])/2., y)
+			}
+		}
+	}
+
+	if bestWidth == 0 {
+		return vector.Vector2{}, errors.New("contour has no interior")
+	}
+	return best, nil
+}
+
+// holePoints finds a point inside every hole of the outlines, avoiding any
+// other outline that happens to sit within a hole.
+func holePoints(outlines []Outline) ([][2]float64, error) {
+	points := make([][2]float64, 0)
+	for outlineIndex, outline := range outlines {
+		for _, hole := range outline.Holes {
+			contours := [][]vector.Vector2{hole.GetPoints()}
+			for otherIndex, other := range outlines {
+				if otherIndex == outlineIndex {
+					continue
+				}
+				if pointInPolygon(other.Outer.GetPoints()[0], hole.GetPoints()) {
+					contours = append(contours, other.Outer.GetPoints())
+				}
+			}
+
+			point, err := interiorPoint(contours)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, [2]float64{point.X(), point.Y()})
+		}
+	}
+	return points, nil
+}
+
+// MirrorX flips the outline across the Y axis, keeping outer contours
+// wound counter clockwise and holes clockwise.
+func (o Outline) MirrorX() Outline {
+	mirror := func(shape mesh.Shape) mesh.Shape {
+		points := shape.GetPoints()
+		mirrored := make([]vector.Vector2, len(points))
+		for i, p := range points {
+			mirrored[len(points)-1-i] = vector.NewVector2(-p.X(), p.Y())
+		}
+		mirroredShape, _ := mesh.NewShape(mirrored)
+		return mirroredShape
+	}
+
+	holes := make([]mesh.Shape, len(o.Holes))
+	for i, hole := range o.Holes {
+		holes[i] = mirror(hole)
+	}
+	return Outline{Outer: mirror(o.Outer), Holes: holes}
+}
+
+// outlinesBounds finds the bottom left and top right corners of the box
+// around every outline.
+func outlinesBounds(outlines []Outline) (vector.Vector2, vector.Vector2) {
+	shapes := make([]mesh.Shape, len(outlines))
+	for i, outline := range outlines {
+		shapes[i] = outline.Outer
+	}
+
+	bottomLeft := vector.NewVector2(math.Inf(1), math.Inf(1))
+	topRight := vector.NewVector2(math.Inf(-1), math.Inf(-1))
+	for _, shape := range shapes {
+		min, max := shape.GetBounds()
+		bottomLeft = vector.NewVector2(math.Min(bottomLeft.X(), min.X()), math.Min(bottomLeft.Y(), min.Y()))
+		topRight = vector.NewVector2(math.Max(topRight.X(), max.X()), math.Max(topRight.Y(), max.Y()))
+	}
+	return bottomLeft, topRight
+}
+
+// outlinesCenter is the center of the box around every outline.
+func outlinesCenter(outlines []Outline) vector.Vector2 {
+	bottomLeft, topRight := outlinesBounds(outlines)
+	return bottomLeft.Add(topRight).MultByConstant(.5)
+}
+
+// translateOutlines moves every outline by the same amount.
+func translateOutlines(outlines []Outline, movement vector.Vector2) []Outline {
+	moved := make([]Outline, len(outlines))
+	for i, outline := range outlines {
+		moved[i] = outline.Translate(movement)
+	}
+	return moved
+}
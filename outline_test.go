@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func square(t *testing.T, size float64, ccw bool) mesh.Shape {
+	points := []vector.Vector2{
+		vector.NewVector2(-size, -size),
+		vector.NewVector2(size, -size),
+		vector.NewVector2(size, size),
+		vector.NewVector2(-size, size),
+	}
+	if !ccw {
+		points = reversePoints(points)
+	}
+	shape, err := mesh.NewShape(points)
+	assert.NoError(t, err)
+	return shape
+}
+
+func TestClassifyContours(t *testing.T) {
+	// TrueType shells are clockwise with counter clockwise holes
+	outlines := classifyContours([]mesh.Shape{
+		square(t, 1, true),
+		square(t, 2, false),
+		square(t, 0.5, false).Translate(vector.NewVector2(10, 0)),
+	})
+
+	if assert.Len(t, outlines, 2) {
+		assert.Greater(t, signedArea(outlines[0].Outer.GetPoints()), 0.)
+		assert.Len(t, outlines[0].Holes, 0)
+
+		assert.Equal(t, 16., signedArea(outlines[1].Outer.GetPoints()))
+		if assert.Len(t, outlines[1].Holes, 1) {
+			assert.Equal(t, -4., signedArea(outlines[1].Holes[0].GetPoints()))
+		}
+	}
+}
+
+func TestInteriorPointAvoidsNestedContours(t *testing.T) {
+	ring := [][]vector.Vector2{
+		square(t, 2, true).GetPoints(),
+		square(t, 1.9, false).GetPoints(),
+	}
+
+	point, err := interiorPoint(ring)
+
+	assert.NoError(t, err)
+	assert.True(t, pointInPolygon(point, ring[0]))
+	assert.False(t, pointInPolygon(point, ring[1]))
+}
+
+func TestInteriorPointOfConcaveContour(t *testing.T) {
+	// A U shape whose bounding box center falls outside of it
+	u := []vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(3, 0),
+		vector.NewVector2(3, 3),
+		vector.NewVector2(2, 3),
+		vector.NewVector2(2, 1),
+		vector.NewVector2(1, 1),
+		vector.NewVector2(1, 3),
+		vector.NewVector2(0, 3),
+	}
+
+	point, err := interiorPoint([][]vector.Vector2{u})
+
+	assert.NoError(t, err)
+	assert.True(t, pointInPolygon(point, u))
+}
+
+func TestHolePointsSkipIslands(t *testing.T) {
+	outlines := []Outline{
+		{Outer: square(t, 3, true), Holes: []mesh.Shape{square(t, 2, false)}},
+		{Outer: square(t, 1, true)},
+	}
+
+	points, err := holePoints(outlines)
+
+	assert.NoError(t, err)
+	if assert.Len(t, points, 1) {
+		point := vector.NewVector2(points[0][0], points[0][1])
+		assert.True(t, pointInPolygon(point, outlines[0].Holes[0].GetPoints()))
+		assert.False(t, pointInPolygon(point, outlines[1].Outer.GetPoints()))
+	}
+}
+
+func TestOutlineMirrorXKeepsWinding(t *testing.T) {
+	outline := Outline{
+		Outer: square(t, 2, true).Translate(vector.NewVector2(1, 0)),
+		Holes: []mesh.Shape{square(t, 1, false).Translate(vector.NewVector2(1, 0))},
+	}
+
+	mirrored := outline.MirrorX()
+
+	assert.Greater(t, signedArea(mirrored.Outer.GetPoints()), 0.)
+	assert.Less(t, signedArea(mirrored.Holes[0].GetPoints()), 0.)
+
+	// Square spanning [-1, 3] in x now spans [-3, 1]
+	min, max := mirrored.Outer.GetBounds()
+	assert.InDelta(t, -3., min.X(), 1e-9)
+	assert.InDelta(t, 1., max.X(), 1e-9)
+}
+
+func TestSegmentsCross(t *testing.T) {
+	p := func(x, y float64) vector.Vector2 { return vector.NewVector2(x, y) }
+
+	assert.True(t, segmentsCross(p(0, 0), p(2, 2), p(0, 2), p(2, 0)))
+	assert.False(t, segmentsCross(p(0, 0), p(1, 0), p(0, 1), p(1, 1)))
+	assert.False(t, segmentsCross(p(0, 0), p(1, 1), p(2, 2), p(3, 3)))
+
+	// Touching counts as crossing
+	assert.True(t, segmentsCross(p(0, 0), p(1, 0), p(1, 0), p(1, 1)))
+	assert.True(t, segmentsCross(p(0, 0), p(2, 0), p(1, 0), p(3, 0)))
+}
+
+func TestCrossesItself(t *testing.T) {
+	p := vector.NewVector2
+	assert.False(t, crossesItself(square(t, 1, true).GetPoints()))
+	assert.False(t, crossesItself([]vector.Vector2{p(0, 0), p(1, 0), p(0, 1)}))
+	assert.True(t, crossesItself([]vector.Vector2{p(0, 0), p(1, 1), p(1, 0), p(0, 1)}))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/EliCDavis/vector"
+)
+
+// edgeProfile is the shape of a medal's side seen edge on.
+type edgeProfile struct {
+	// points of the side from the bottom of a medal of the given thickness
+	// to its top, each as how far the side sticks out past the medal's
+	// outline (X) and how high up it is (Y)
+	points func(edge edgeSpec, thickness float64) []vector.Vector2
+
+	// defaultSize is the size of the profile when the spec doesn't give
+	// one, where inset is the furthest the side can cut in without passing
+	// the rims or meeting itself. Profiles without a size leave it nil.
+	defaultSize func(inset float64) float64
+}
+
+// bulgeSize is the default size of profiles that bulge out, which never
+// run into the rims.
+func bulgeSize(inset float64) float64 {
+	return defaultBulge
+}
+
+// insetSize is the default size of profiles that cut in, keeping their
+// corners well clear of the rims.
+func insetSize(inset float64) float64 {
+	return inset / 2
+}
+
+// edgeProfiles are all the profiles a medal spec can select by name.
+var edgeProfiles = map[string]edgeProfile{
+	"flat": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			return []vector.Vector2{
+				vector.NewVector2(0, 0),
+				vector.NewVector2(0, thickness),
+			}
+		},
+	},
+	"sine": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			return sineProfile(edge.Size, edge.Resolution, thickness)
+		},
+		defaultSize: bulgeSize,
+	},
+	"chamfer": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			return []vector.Vector2{
+				vector.NewVector2(-edge.Size, 0),
+				vector.NewVector2(0, edge.Size),
+				vector.NewVector2(0, thickness-edge.Size),
+				vector.NewVector2(-edge.Size, thickness),
+			}
+		},
+		defaultSize: insetSize,
+	},
+	"round-over": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			// Quarter circles round off the bottom and top corners
+			points := make([]vector.Vector2, 0, (edge.Resolution+1)*2)
+			for i := 0; i <= edge.Resolution; i++ {
+				angle := (math.Pi / 2) * float64(i) / float64(edge.Resolution)
+				points = append(points, vector.NewVector2(
+					-edge.Size+(edge.Size*math.Sin(angle)),
+					edge.Size-(edge.Size*math.Cos(angle)),
+				))
+			}
+			for i := 0; i <= edge.Resolution; i++ {
+				angle := (math.Pi / 2) * float64(i) / float64(edge.Resolution)
+				points = append(points, vector.NewVector2(
+					-edge.Size+(edge.Size*math.Cos(angle)),
+					thickness-edge.Size+(edge.Size*math.Sin(angle)),
+				))
+			}
+			return points
+		},
+		defaultSize: insetSize,
+	},
+	"ogee": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			// An S curve that bows out below the middle and curls back in
+			// above it, crossing the outline halfway up
+			points := make([]vector.Vector2, edge.Resolution+1)
+			for i := range points {
+				t := float64(i) / float64(edge.Resolution)
+				points[i] = vector.NewVector2(edge.Size*math.Sin(2*math.Pi*t), thickness*t)
+			}
+			return points
+		},
+		defaultSize: bulgeSize,
+	},
+	"stepped": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			// Each step up sits further in than the one below it
+			points := make([]vector.Vector2, 0, edge.Steps*2)
+			for step := 0; step < edge.Steps; step++ {
+				offset := -edge.Size * float64(step) / float64(edge.Steps)
+				points = append(points,
+					vector.NewVector2(offset, thickness*float64(step)/float64(edge.Steps)),
+					vector.NewVector2(offset, thickness*float64(step+1)/float64(edge.Steps)),
+				)
+			}
+			return points
+		},
+		defaultSize: insetSize,
+	},
+	"spline": {
+		points: func(edge edgeSpec, thickness float64) []vector.Vector2 {
+			controls := make([]vector.Vector2, len(edge.Points))
+			for i, point := range edge.Points {
+				controls[i] = vector.NewVector2(point[0], point[1]*thickness)
+			}
+			return catmullRom(controls, edge.Resolution)
+		},
+	},
+}
+
+// edgeProfileNames lists every profile in edgeProfiles in alphabetical
+// order.
+func edgeProfileNames() []string {
+	names := make([]string, 0, len(edgeProfiles))
+	for name := range edgeProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sineProfile bulges the side out by up to bulge halfway up, following
+// half a sine wave drawn with the given number of segments.
+func sineProfile(bulge float64, resolution int, thickness float64) []vector.Vector2 {
+	points := make([]vector.Vector2, resolution+1)
+	for i := range points {
+		t := float64(i) / float64(resolution)
+		points[i] = vector.NewVector2(bulge*math.Sin(math.Pi*t), thickness*t)
+	}
+	return points
+}
+
+// catmullRom draws a smooth curve through every control point, with
+// resolution segments between each of them.
+func catmullRom(controls []vector.Vector2, resolution int) []vector.Vector2 {
+	if len(controls) < 2 {
+		return controls
+	}
+
+	// The ends are repeated so the curve reaches the first and last points
+	padded := append([]vector.Vector2{controls[0]}, controls...)
+	padded = append(padded, controls[len(controls)-1])
+
+	points := []vector.Vector2{controls[0]}
+	for i := 1; i < len(padded)-2; i++ {
+		p0, p1, p2, p3 := padded[i-1], padded[i], padded[i+1], padded[i+2]
+		for step := 1; step <= resolution; step++ {
+			t := float64(step) / float64(resolution)
+			t2 := t * t
+			t3 := t2 * t
+			point := p1.MultByConstant(2).
+				Add(p2.Sub(p0).MultByConstant(t)).
+				Add(p0.MultByConstant(2).Sub(p1.MultByConstant(5)).Add(p2.MultByConstant(4)).Sub(p3).MultByConstant(t2)).
+				Add(p1.MultByConstant(3).Sub(p0).Sub(p2.MultByConstant(3)).Add(p3).MultByConstant(t3)).
+				MultByConstant(.5)
+			points = append(points, point)
+		}
+	}
+	return points
+}
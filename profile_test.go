@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdgeProfilesRunFromBottomToTop(t *testing.T) {
+	edge := edgeSpec{
+		Size:       .05,
+		Resolution: 8,
+		Steps:      3,
+		Points:     [][2]float64{{0, 0}, {.1, .5}, {0, 1}},
+	}
+
+	for _, name := range edgeProfileNames() {
+		points := edgeProfiles[name].points(edge, .3)
+		if !assert.GreaterOrEqual(t, len(points), 2, name) {
+			continue
+		}
+		assert.InDelta(t, 0., points[0].Y(), 1e-9, name)
+		assert.InDelta(t, .3, points[len(points)-1].Y(), 1e-9, name)
+		for i := 1; i < len(points); i++ {
+			assert.GreaterOrEqual(t, points[i].Y(), points[i-1].Y()-1e-9, name)
+		}
+	}
+}
+
+func TestSineProfileMatchesTheOriginalBulge(t *testing.T) {
+	points := sineProfile(defaultBulge, defaultBulgeResolution, .3)
+	if assert.Len(t, points, defaultBulgeResolution+1) {
+		assert.InDelta(t, 0., points[0].X(), 1e-9)
+		assert.InDelta(t, defaultBulge, points[5].X(), 1e-9)
+		assert.InDelta(t, .15, points[5].Y(), 1e-9)
+		assert.InDelta(t, 0., points[10].X(), 1e-9)
+	}
+}
+
+func TestOgeeProfileBowsOutThenCurlsIn(t *testing.T) {
+	points := edgeProfiles["ogee"].points(edgeSpec{Size: .05, Resolution: 8}, .3)
+	if !assert.Len(t, points, 9) {
+		return
+	}
+	assert.InDelta(t, .05, points[2].X(), 1e-9)
+	assert.InDelta(t, 0., points[4].X(), 1e-9)
+	assert.InDelta(t, .15, points[4].Y(), 1e-9)
+	assert.InDelta(t, -.05, points[6].X(), 1e-9)
+	assert.InDelta(t, 0., points[8].X(), 1e-9)
+}
+
+func TestRoundOverProfileIsRound(t *testing.T) {
+	points := edgeProfiles["round-over"].points(edgeSpec{Size: .05, Resolution: 8}, .3)
+
+	bottom := vector.NewVector2(-.05, .05)
+	top := vector.NewVector2(-.05, .25)
+	for i, p := range points {
+		center := bottom
+		if i > 8 {
+			center = top
+		}
+		assert.InDelta(t, .05, p.Distance(center), 1e-9)
+	}
+}
+
+func TestCatmullRomPassesThroughItsControls(t *testing.T) {
+	controls := []vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(.1, .5),
+		vector.NewVector2(0, 1),
+	}
+
+	points := catmullRom(controls, 4)
+	if assert.Len(t, points, 9) {
+		assert.Equal(t, controls[0], points[0])
+		assert.InDelta(t, .1, points[4].X(), 1e-9)
+		assert.InDelta(t, .5, points[4].Y(), 1e-9)
+		assert.InDelta(t, 0., points[8].X(), 1e-9)
+		assert.InDelta(t, 1., points[8].Y(), 1e-9)
+	}
+
+	// Between the controls the curve is smooth, not a straight line
+	assert.Greater(t, points[2].X(), .05+1e-3)
+	assert.False(t, math.IsNaN(points[6].X()))
+}
+
+func TestMedalionSideFollowsItsProfile(t *testing.T) {
+	side := edgeProfiles["chamfer"].points(edgeSpec{Size: .02}, .3)
+	body, rim, _, err := makeMedalionParts(circleBody(16, 1), side, .1, .05, engravedFace(nil), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Three rings of 16 sides make up the chamfered side, with everything
+	// else lying flat
+	walls := 0
+	for _, face := range body.GetFaces() {
+		vertices := face.GetVertices()
+		if vertices[0].Y() == vertices[1].Y() && vertices[1].Y() == vertices[2].Y() {
+			continue
+		}
+		walls++
+		for _, v := range vertices {
+			radius := math.Hypot(v.X(), v.Z())
+			assert.LessOrEqual(t, radius, 1+1e-9)
+			assert.GreaterOrEqual(t, radius, .98-1e-9)
+		}
+	}
+	assert.Equal(t, 3*16*2, walls)
+
+	// The rim starts from where the chamfer ends
+	for _, face := range rim.GetFaces() {
+		for _, v := range face.GetVertices() {
+			assert.LessOrEqual(t, math.Hypot(v.X(), v.Z()), .98+1e-9)
+		}
+	}
+}
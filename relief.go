@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+
+	// Images are decoded by whichever of these formats they're in
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/pradeep-pyro/triangle"
+)
+
+// heightmap is the luminance of every pixel of an image, from 0 for black
+// to 1 for white. Transparent pixels count as black.
+type heightmap struct {
+	width  int
+	height int
+	values []float64
+}
+
+func newHeightmap(img image.Image) *heightmap {
+	bounds := img.Bounds()
+	h := &heightmap{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		values: make([]float64, bounds.Dx()*bounds.Dy()),
+	}
+
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			// Colors come back premultiplied by alpha, which is what fades
+			// transparent pixels to black
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			luminance := (.2126 * float64(r)) + (.7152 * float64(g)) + (.0722 * float64(b))
+			h.values[(y*h.width)+x] = luminance / 0xffff
+		}
+	}
+	return h
+}
+
+// loadHeightmap reads the PNG or JPEG at path.
+func loadHeightmap(path string) (*heightmap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image %s: %w", path, err)
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("image %s has no pixels", path)
+	}
+	return newHeightmap(img), nil
+}
+
+// at is the value of the pixel, clamping coordinates that fall off the
+// image to its edge.
+func (h *heightmap) at(x, y int) float64 {
+	x = int(math.Max(0, math.Min(float64(h.width-1), float64(x))))
+	y = int(math.Max(0, math.Min(float64(h.height-1), float64(y))))
+	return h.values[(y*h.width)+x]
+}
+
+// sample blends the four pixels around a point, given in pixels from the
+// top left corner of the image.
+func (h *heightmap) sample(x, y float64) float64 {
+	// Pixel centers sit half way into each pixel
+	x -= .5
+	y -= .5
+	left, top := math.Floor(x), math.Floor(y)
+	tx, ty := x-left, y-top
+	l, t := int(left), int(top)
+
+	upper := (h.at(l, t) * (1 - tx)) + (h.at(l+1, t) * tx)
+	lower := (h.at(l, t+1) * (1 - tx)) + (h.at(l+1, t+1) * tx)
+	return (upper * (1 - ty)) + (lower * ty)
+}
+
+// smooth blurs the heightmap with a gaussian, radius pixels across for each
+// standard deviation. Smoothing keeps noise in photographs from turning into
+// spikes in the relief.
+func (h *heightmap) smooth(radius float64) *heightmap {
+	if radius <= 0 {
+		return h
+	}
+
+	reach := int(math.Ceil(radius * 3))
+	kernel := make([]float64, (reach*2)+1)
+	total := 0.
+	for i := range kernel {
+		offset := float64(i - reach)
+		kernel[i] = math.Exp(-(offset * offset) / (2 * radius * radius))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+
+	// Gaussians blur one axis at a time
+	blur := func(source *heightmap, dx, dy int) *heightmap {
+		blurred := &heightmap{width: source.width, height: source.height, values: make([]float64, len(source.values))}
+		for y := 0; y < source.height; y++ {
+			for x := 0; x < source.width; x++ {
+				value := 0.
+				for i, weight := range kernel {
+					offset := i - reach
+					value += weight * source.at(x+(offset*dx), y+(offset*dy))
+				}
+				blurred.values[(y*source.width)+x] = value
+			}
+		}
+		return blurred
+	}
+	return blur(blur(h, 1, 0), 0, 1)
+}
+
+// relief is an image raised out of the design face, with white pixels
+// standing height above the face and black pixels left flat.
+type relief struct {
+	heights *heightmap
+	height  float64
+
+	// invert raises black pixels instead of white ones
+	invert bool
+
+	// maxTriangles is the most triangles the face can be made of
+	maxTriangles int
+}
+
+// distanceToContour is how far the point is from the closest edge of the
+// closed contour.
+func distanceToContour(point vector.Vector2, contour []vector.Vector2) float64 {
+	closest := math.Inf(1)
+	for i, start := range contour {
+		end := contour[(i+1)%len(contour)]
+		edge := end.Sub(start)
+		t := 0.
+		if lengthSquared := edge.Dot(edge); lengthSquared > 0 {
+			t = math.Max(0, math.Min(1, point.Sub(start).Dot(edge)/lengthSquared))
+		}
+		closest = math.Min(closest, point.Distance(start.Add(edge.MultByConstant(t))))
+	}
+	return closest
+}
+
+// reliefGrid is the points to sample the image at inside the outline,
+// spaced evenly so that triangulating them along with the outline makes no
+// more than maxTriangles triangles, and never closer than minSpacing.
+func reliefGrid(outline []vector.Vector2, maxTriangles int, minSpacing float64) []vector.Vector2 {
+	// Triangulating n points inside a polygon of m points makes 2n + m - 2
+	// triangles
+	budget := (maxTriangles - len(outline) + 2) / 2
+	if budget <= 0 {
+		return nil
+	}
+
+	spacing := math.Max(math.Sqrt(math.Abs(signedArea(outline))/float64(budget)), minSpacing)
+
+	shape, _ := mesh.NewShape(outline)
+	min, max := shape.GetBounds()
+
+	// Points right up against the outline would make slivers along the rim
+	margin := spacing / 2
+
+	points := make([]vector.Vector2, 0, budget)
+	for y := min.Y() + margin; y < max.Y(); y += spacing {
+		for x := min.X() + margin; x < max.X(); x += spacing {
+			p := vector.NewVector2(x, y)
+			if pointInPolygon(p, outline) && distanceToContour(p, outline) > margin {
+				points = append(points, p)
+			}
+		}
+	}
+
+	// Rounding at the edges of the outline can leave a few too many
+	if len(points) > budget {
+		points = points[:budget]
+	}
+	return points
+}
+
+// reliefFace is a design face with the image raised out of it. The image
+// covers the bounds of the face like a photo in a frame, cropping whatever
+// sticks out past its shorter side. The edge of the face stays flat so it
+// meets the rim.
+func reliefFace(r relief) medalFace {
+	return func(outline []vector.Vector2, height float64) ([]mesh.Polygon, error) {
+		return makeRelief(outline, height, r)
+	}
+}
+
+func makeRelief(outline []vector.Vector2, height float64, r relief) ([]mesh.Polygon, error) {
+	if len(outline) < 3 {
+		return nil, errors.New("Can't make a polygon with less than 3 points")
+	}
+	if r.heights == nil {
+		return nil, errors.New("relief needs an image to raise")
+	}
+
+	shape, err := mesh.NewShape(outline)
+	if err != nil {
+		return nil, err
+	}
+	min, max := shape.GetBounds()
+	size := max.Sub(min)
+	center := min.Add(max).MultByConstant(.5)
+
+	// Looking down at the face X points left and Y points up, the opposite
+	// of both axes of an image
+	pixelsPerUnit := math.Max(float64(r.heights.width)/size.X(), float64(r.heights.height)/size.Y())
+	toPixel := func(x, y float64) (float64, float64) {
+		return ((center.X() - x) * pixelsPerUnit) + (float64(r.heights.width) / 2),
+			((center.Y() - y) * pixelsPerUnit) + (float64(r.heights.height) / 2)
+	}
+
+	// Sampling finer than a pixel adds triangles without adding detail
+	grid := reliefGrid(outline, r.maxTriangles, 1/pixelsPerUnit)
+
+	flatPoints := make([][2]float64, 0, len(outline)+len(grid))
+	segments := make([][2]int32, len(outline))
+	for i, p := range outline {
+		flatPoints = append(flatPoints, [2]float64{p.X(), p.Y()})
+		segments[i] = [2]int32{int32(i), int32((i + 1) % len(outline))}
+	}
+	for _, p := range grid {
+		flatPoints = append(flatPoints, [2]float64{p.X(), p.Y()})
+	}
+
+	v, faces := triangle.ConstrainedDelaunay(flatPoints, segments, nil)
+
+	elevation := func(i int32) float64 {
+		if i < int32(len(outline)) || distanceToContour(vector.NewVector2(v[i][0], v[i][1]), outline) < 1e-9 {
+			return height
+		}
+		value := r.heights.sample(toPixel(v[i][0], v[i][1]))
+		if r.invert {
+			value = 1 - value
+		}
+		return height + (value * r.height)
+	}
+
+	polys := make([]mesh.Polygon, len(faces))
+	for i, face := range orientUp(v, faces) {
+		verts := make([]vector.Vector3, 3)
+		uvs := make([]vector.Vector2, 3)
+		for corner, index := range face {
+			verts[corner] = vector.NewVector3(v[index][0], elevation(index), v[index][1])
+
+			// Textured with the same image the relief came from
+			px, py := toPixel(v[index][0], v[index][1])
+			uvs[corner] = vector.NewVector2(px/float64(r.heights.width), 1-(py/float64(r.heights.height)))
+		}
+		poly, err := mesh.NewPolygonWithTexture(verts, verts, uvs)
+		if err != nil {
+			return nil, err
+		}
+		polys[i] = poly
+	}
+	return polys, nil
+}
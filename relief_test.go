@@ -0,0 +1,113 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeightmapUsesLuminance(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.White)
+	img.Set(1, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(0, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 0})
+	img.Set(1, 1, color.Black)
+
+	h := newHeightmap(img)
+	assert.InDelta(t, 1, h.at(0, 0), 1e-9)
+	assert.InDelta(t, .2126, h.at(1, 0), 1e-9)
+	assert.InDelta(t, 0, h.at(0, 1), 1e-9, "transparent pixels are black")
+	assert.InDelta(t, 0, h.at(1, 1), 1e-9)
+
+	// Off the image clamps to the edge
+	assert.InDelta(t, 1, h.at(-3, -3), 1e-9)
+}
+
+func TestHeightmapSampleBlendsPixels(t *testing.T) {
+	h := &heightmap{width: 2, height: 1, values: []float64{0, 1}}
+
+	assert.InDelta(t, 0, h.sample(.5, .5), 1e-9)
+	assert.InDelta(t, .5, h.sample(1, .5), 1e-9)
+	assert.InDelta(t, 1, h.sample(1.5, .5), 1e-9)
+	assert.InDelta(t, 1, h.sample(10, .5), 1e-9)
+}
+
+func TestHeightmapSmoothSpreadsPeaks(t *testing.T) {
+	h := &heightmap{width: 9, height: 9, values: make([]float64, 81)}
+	h.values[40] = 1
+
+	smoothed := h.smooth(1)
+	total := 0.
+	for _, value := range smoothed.values {
+		total += value
+	}
+	assert.InDelta(t, 1, total, 1e-6)
+	assert.Less(t, smoothed.at(4, 4), 1.)
+	assert.Greater(t, smoothed.at(5, 4), 0.)
+	assert.InDelta(t, smoothed.at(5, 4), smoothed.at(4, 3), 1e-12)
+
+	assert.Same(t, h, h.smooth(0))
+}
+
+func TestReliefGridStaysInsideBudget(t *testing.T) {
+	outline := circleOutline(64, 1)
+
+	grid := reliefGrid(outline, 2000, 0)
+	assert.LessOrEqual(t, (2*len(grid))+len(outline)-2, 2000)
+	assert.Greater(t, len(grid), 800)
+	for _, p := range grid {
+		assert.True(t, pointInPolygon(p, outline))
+	}
+
+	// Never finer than a pixel
+	coarse := reliefGrid(outline, 2000, .5)
+	assert.Less(t, len(coarse), 20)
+
+	assert.Empty(t, reliefGrid(outline, 10, 0))
+}
+
+func TestDistanceToContour(t *testing.T) {
+	square := []vector.Vector2{
+		vector.NewVector2(0, 0),
+		vector.NewVector2(2, 0),
+		vector.NewVector2(2, 2),
+		vector.NewVector2(0, 2),
+	}
+
+	assert.InDelta(t, 1, distanceToContour(vector.NewVector2(1, 1), square), 1e-9)
+	assert.InDelta(t, .5, distanceToContour(vector.NewVector2(1.5, 1), square), 1e-9)
+	assert.InDelta(t, 5, distanceToContour(vector.NewVector2(5, 6), square), 1e-9)
+}
+
+func TestMedalAssetsSmoothEachImageOnce(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.White)
+
+	path := filepath.Join(t.TempDir(), "portrait.png")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, png.Encode(f, img))
+	assert.NoError(t, f.Close())
+
+	assets := newMedalAssets()
+	smoothed, err := assets.smoothHeightmap(path, 1)
+	assert.NoError(t, err)
+	again, err := assets.smoothHeightmap(path, 1)
+	assert.NoError(t, err)
+	assert.Same(t, smoothed, again)
+
+	sharp, err := assets.smoothHeightmap(path, 0)
+	assert.NoError(t, err)
+	assert.NotSame(t, smoothed, sharp)
+	assert.InDelta(t, 1, sharp.at(1, 1), 1e-9)
+	assert.Less(t, smoothed.at(1, 1), 1.)
+
+	_, err = assets.smoothHeightmap(filepath.Join(t.TempDir(), "missing.png"), 1)
+	assert.Error(t, err)
+}
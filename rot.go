@@ -14,7 +14,13 @@ func Rotate(m mesh.Model, pivot vector.Vector3, rot mesh.Quaternion) mesh.Model
 		for _, v := range f.GetVertices() {
 			vertRotated = append(vertRotated, rot.Rotate(v.Sub(pivot)).Add(pivot))
 		}
-		p, _ := mesh.NewPolygon(vertRotated, vertRotated)
+		// Keep texture coordinates so logos don't lose their materials
+		var p mesh.Polygon
+		if uvs := f.GetUVs(); len(uvs) == len(vertRotated) {
+			p, _ = mesh.NewPolygonWithTexture(vertRotated, vertRotated, uvs)
+		} else {
+			p, _ = mesh.NewPolygon(vertRotated, vertRotated)
+		}
 		polysRotated = append(polysRotated, p)
 	}
 
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// circleOutline is a circle with the given number of sides, wound counter
+// clockwise. It starts at the same angle as the rings made by makeRing so
+// the two line up exactly when they share a resolution.
+func circleOutline(sides int, radius float64) []vector.Vector2 {
+	points := make([]vector.Vector2, sides)
+	angleIncrement := (1.0 / float64(sides)) * 2.0 * math.Pi
+	for sideIndex := range points {
+		angle := angleIncrement * float64(sideIndex)
+		points[sideIndex] = vector.NewVector2(math.Cos(angle)*radius, math.Sin(angle)*radius)
+	}
+	return points
+}
+
+// regularPolygonOutline is a polygon with the given number of equal sides
+// whose corners all sit radius away from the center, wound counter
+// clockwise with a flat side along the bottom.
+func regularPolygonOutline(sides int, radius float64) []vector.Vector2 {
+	points := make([]vector.Vector2, sides)
+	angleIncrement := (1.0 / float64(sides)) * 2.0 * math.Pi
+	start := -(math.Pi / 2.0) + (angleIncrement / 2.0)
+	for sideIndex := range points {
+		angle := start + (angleIncrement * float64(sideIndex))
+		points[sideIndex] = vector.NewVector2(math.Cos(angle)*radius, math.Sin(angle)*radius)
+	}
+	return points
+}
+
+// starOutline is a star with the given number of points, alternating
+// between the outer and inner radius, wound counter clockwise with a point
+// facing straight up.
+func starOutline(points int, outerRadius, innerRadius float64) []vector.Vector2 {
+	star := make([]vector.Vector2, points*2)
+	angleIncrement := math.Pi / float64(points)
+	for i := range star {
+		radius := outerRadius
+		if i%2 == 1 {
+			radius = innerRadius
+		}
+		angle := (math.Pi / 2.0) + (angleIncrement * float64(i))
+		star[i] = vector.NewVector2(math.Cos(angle)*radius, math.Sin(angle)*radius)
+	}
+	return star
+}
+
+// shieldOutline is a heraldic shield centered on the origin with a flat
+// top and sides that curve down into a point, wound counter clockwise.
+// Resolution is how many segments make up each curved side.
+func shieldOutline(width, height float64, resolution int) []vector.Vector2 {
+	halfWidth := width / 2.0
+	halfHeight := height / 2.0
+
+	// The sides run straight down for the top third of the shield before
+	// curving into the point at the bottom
+	shoulder := halfHeight - (height / 3.0)
+
+	points := []vector.Vector2{
+		vector.NewVector2(halfWidth, halfHeight),
+		vector.NewVector2(-halfWidth, halfHeight),
+	}
+
+	// Down the left side to the point
+	for i := 0; i < resolution; i++ {
+		angle := (math.Pi / 2.0) * float64(i) / float64(resolution)
+		points = append(points, vector.NewVector2(
+			-halfWidth*math.Cos(angle),
+			shoulder-((shoulder+halfHeight)*math.Sin(angle)),
+		))
+	}
+	points = append(points, vector.NewVector2(0, -halfHeight))
+
+	// And back up the right
+	for i := resolution - 1; i >= 0; i-- {
+		angle := (math.Pi / 2.0) * float64(i) / float64(resolution)
+		points = append(points, vector.NewVector2(
+			halfWidth*math.Cos(angle),
+			shoulder-((shoulder+halfHeight)*math.Sin(angle)),
+		))
+	}
+
+	return points
+}
+
+// maxMiter is the furthest a corner of an outline moves when it's offset,
+// as a multiple of the offset, so sharp points don't shoot off into the
+// distance.
+const maxMiter = 4.
+
+// offsetOutline grows the counter clockwise outline out by distance, or
+// shrinks it in when distance is negative. Every corner moves along the
+// line halfway between its two sides, far enough for both sides to end up
+// distance away from where they were, so the outline keeps one point for
+// every point it started with.
+func offsetOutline(outline []vector.Vector2, distance float64) []vector.Vector2 {
+	if distance == 0 {
+		return outline
+	}
+
+	// Counter clockwise outlines have their outside on the right of every
+	// side
+	normal := func(start, end vector.Vector2) vector.Vector2 {
+		side := end.Sub(start)
+		return vector.NewVector2(side.Y(), -side.X()).Normalized()
+	}
+
+	offset := make([]vector.Vector2, len(outline))
+	for i, point := range outline {
+		previous := normal(outline[(i+len(outline)-1)%len(outline)], point)
+		next := normal(point, outline[(i+1)%len(outline)])
+
+		// Sides that double straight back on each other have no corner to
+		// split, so the point moves out along the first
+		bisector := previous.Add(next)
+		if bisector.Length() < 1e-9 {
+			offset[i] = point.Add(previous.MultByConstant(distance))
+			continue
+		}
+		bisector = bisector.Normalized()
+
+		miter := 1 / math.Max(bisector.Dot(previous), 1/maxMiter)
+		offset[i] = point.Add(bisector.MultByConstant(distance * miter))
+	}
+	return offset
+}
+
+// inscribedRadius is how far it is from the center of the face to the
+// nearest side of the outline.
+func inscribedRadius(outline []vector.Vector2) float64 {
+	return distanceToContour(vector.Vector2Zero(), outline)
+}
+
+// bodyOutline is the outline of a medal's body looking down on it, grown
+// out by offset or shrunk in by a negative offset. Every outline it gives
+// for a body has the same number of points, each lined up with the same
+// point of the others, so rings can be stitched between them.
+type bodyOutline func(offset float64) []vector.Vector2
+
+// circleBody is a round medal made of the given number of sides.
+func circleBody(sides int, radius float64) bodyOutline {
+	return func(offset float64) []vector.Vector2 {
+		return circleOutline(sides, radius+offset)
+	}
+}
+
+// shapedBody is a medal in the shape of the counter clockwise outline.
+func shapedBody(outline []vector.Vector2) bodyOutline {
+	return func(offset float64) []vector.Vector2 {
+		return offsetOutline(outline, offset)
+	}
+}
+
+// mirroredBody is the outline as it's seen looking up at the back of the
+// medal, where X points the other way. Points are reversed so the mirrored
+// outline still runs counter clockwise, and every offset keeps the same
+// number of points.
+func mirroredBody(outline bodyOutline) bodyOutline {
+	return func(offset float64) []vector.Vector2 {
+		points := outline(offset)
+		mirrored := make([]vector.Vector2, len(points))
+		for i, p := range points {
+			mirrored[len(points)-1-i] = vector.NewVector2(-p.X(), p.Y())
+		}
+		return mirrored
+	}
+}
+
+// checkBorder makes sure the face left inside a rim of the given border
+// doesn't cross itself, which happens when the border is wider than half of
+// some narrow part of the outline.
+func checkBorder(outline bodyOutline, border float64) error {
+	if crossesItself(outline(-border)) {
+		return fmt.Errorf("rim border %g is too wide for the medal's shape, the face inside it crosses itself", border)
+	}
+	return nil
+}
+
+// shieldAspect is how wide a shield shaped medal is compared to how tall it
+// is.
+const shieldAspect = .8
+
+// fitOutline turns the largest shape out of an SVG into a medal outline
+// centered on the face whose furthest point is radius from the center.
+// Holes in the shape are ignored.
+func fitOutline(outlines []Outline, radius float64) ([]vector.Vector2, error) {
+	largest, center, scale, err := fitShape(outlines, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	// SVGs have Y pointing down and the face has X pointing left, so the
+	// shape is turned half way around to read the right way round
+	fitted := make([]vector.Vector2, len(largest))
+	for i, p := range largest {
+		fitted[i] = p.Sub(center).MultByConstant(-scale)
+	}
+	if signedArea(fitted) < 0 {
+		fitted = reversePoints(fitted)
+	}
+	return fitted, nil
+}
+
+// fitShape finds the largest shape out of an SVG along with the center and
+// scale fitOutline places it on the face with.
+func fitShape(outlines []Outline, radius float64) ([]vector.Vector2, vector.Vector2, float64, error) {
+	if len(outlines) == 0 {
+		return nil, vector.Vector2Zero(), 0, errors.New("no shapes to make an outline from")
+	}
+
+	largest := outlines[0].Outer.GetPoints()
+	for _, outline := range outlines[1:] {
+		if points := outline.Outer.GetPoints(); math.Abs(signedArea(points)) > math.Abs(signedArea(largest)) {
+			largest = points
+		}
+	}
+
+	shape, err := mesh.NewShape(largest)
+	if err != nil {
+		return nil, vector.Vector2Zero(), 0, err
+	}
+	min, max := shape.GetBounds()
+	center := min.Add(max).MultByConstant(.5)
+
+	furthest := 0.
+	for _, p := range largest {
+		furthest = math.Max(furthest, p.Sub(center).Length())
+	}
+	if furthest == 0 {
+		return nil, vector.Vector2Zero(), 0, errors.New("shape has no size to make an outline from")
+	}
+	return largest, center, radius / furthest, nil
+}
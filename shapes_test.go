@@ -0,0 +1,315 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircleOutlineLinesUpWithRing(t *testing.T) {
+	outline := circleOutline(16, 2)
+	ring := makeRing(16, 0, 1, 2, 2)
+
+	ringPoints := make(map[[2]float64]bool)
+	for _, face := range ring {
+		for _, v := range face.GetVertices() {
+			ringPoints[[2]float64{v.X(), v.Z()}] = true
+		}
+	}
+
+	assert.Len(t, outline, 16)
+	for _, p := range outline {
+		assert.True(t, ringPoints[[2]float64{p.X(), p.Y()}], "point %v isn't on the ring", p)
+	}
+}
+
+func TestOutlinesWindCounterClockwise(t *testing.T) {
+	outlines := map[string][]vector.Vector2{
+		"circle":  circleOutline(32, 1),
+		"hexagon": regularPolygonOutline(6, 1),
+		"star":    starOutline(5, 1, .4),
+		"shield":  shieldOutline(1, 1.2, 8),
+	}
+	for name, outline := range outlines {
+		assert.Greater(t, signedArea(outline), 0., name)
+	}
+}
+
+func TestStarOutlineAlternatesRadius(t *testing.T) {
+	star := starOutline(5, 1, .4)
+
+	assert.Len(t, star, 10)
+	assert.InDelta(t, 1., star[0].Y(), 1e-9)
+	for i, p := range star {
+		expected := 1.
+		if i%2 == 1 {
+			expected = .4
+		}
+		assert.InDelta(t, expected, p.Length(), 1e-9)
+	}
+}
+
+func TestShieldOutlineBounds(t *testing.T) {
+	shield := shieldOutline(1, 1.2, 8)
+
+	for _, p := range shield {
+		assert.LessOrEqual(t, p.X(), .5+1e-9)
+		assert.GreaterOrEqual(t, p.X(), -.5-1e-9)
+		assert.LessOrEqual(t, p.Y(), .6+1e-9)
+		assert.GreaterOrEqual(t, p.Y(), -.6-1e-9)
+	}
+	assert.Contains(t, shield, vector.NewVector2(0, -.6))
+}
+
+func TestOffsetOutlineKeepsSidesParallel(t *testing.T) {
+	square := []vector.Vector2{
+		vector.NewVector2(-1, -1),
+		vector.NewVector2(1, -1),
+		vector.NewVector2(1, 1),
+		vector.NewVector2(-1, 1),
+	}
+
+	grown := offsetOutline(square, .5)
+	shrunk := offsetOutline(square, -.5)
+	if assert.Len(t, grown, 4) && assert.Len(t, shrunk, 4) {
+		for i, p := range square {
+			assert.InDelta(t, p.X()*1.5, grown[i].X(), 1e-9)
+			assert.InDelta(t, p.Y()*1.5, grown[i].Y(), 1e-9)
+			assert.InDelta(t, p.X()*.5, shrunk[i].X(), 1e-9)
+			assert.InDelta(t, p.Y()*.5, shrunk[i].Y(), 1e-9)
+		}
+	}
+	assert.InDelta(t, .5, inscribedRadius(shrunk), 1e-9)
+
+	// Sharp points don't shoot off further than the miter limit
+	star := starOutline(5, 1, .2)
+	for i, p := range offsetOutline(star, -.1) {
+		assert.LessOrEqual(t, p.Distance(star[i]), (.1*maxMiter)+1e-9)
+	}
+}
+
+func TestCircleBodyIsACircle(t *testing.T) {
+	body := circleBody(16, 2)
+	assert.Equal(t, circleOutline(16, 2), body(0))
+	assert.Equal(t, circleOutline(16, 1.5), body(-.5))
+}
+
+func TestFitOutlineScalesAndTurnsSVGShapes(t *testing.T) {
+	outlines, err := readSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+	<circle cx="50" cy="50" r="5"/>
+	<polygon points="50,0 100,100 0,100"/>
+</svg>`), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	outline, err := fitOutline(outlines, 2)
+	if !assert.NoError(t, err) || !assert.Len(t, outline, 3) {
+		return
+	}
+	assert.Greater(t, signedArea(outline), 0.)
+
+	// The point at the top of the SVG stays at the top of the face, with
+	// the corners at the bottom furthest from the center
+	top := outline[0]
+	for _, p := range outline {
+		assert.LessOrEqual(t, p.Length(), 2+1e-9)
+		if p.Y() > top.Y() {
+			top = p
+		}
+	}
+	assert.InDelta(t, 2*50/math.Hypot(50, 50), top.Y(), 1e-9)
+	assert.InDelta(t, 0., top.X(), 1e-9)
+
+	_, err = fitOutline(nil, 2)
+	assert.EqualError(t, err, "no shapes to make an outline from")
+}
+
+func TestMedalionFollowsItsOutline(t *testing.T) {
+	star := shapedBody(starOutline(5, 1, .5))
+	_, rim, _, err := makeMedalionParts(star, sineProfile(defaultBulge, defaultBulgeResolution, .3), .1, .05, engravedFace(nil), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	corners := make(map[[2]float64]bool)
+	for _, outline := range [][]vector.Vector2{star(0), star(-.05)} {
+		for _, p := range outline {
+			corners[[2]float64{p.X(), p.Y()}] = true
+		}
+	}
+
+	assert.Len(t, rim.GetFaces(), 40)
+	for _, face := range rim.GetFaces() {
+		for _, v := range face.GetVertices() {
+			assert.True(t, corners[[2]float64{v.X(), v.Z()}], "rim point %v isn't on the star", v)
+		}
+	}
+}
+
+// fanFace is a flat design face made of a fan of triangles out from the
+// middle of the outline, facing up.
+func fanFace(outline []vector.Vector2, height float64) ([]mesh.Polygon, error) {
+	center := vector.Vector2Zero()
+	for _, p := range outline {
+		center = center.Add(p.DivByConstant(float64(len(outline))))
+	}
+
+	polys := make([]mesh.Polygon, len(outline))
+	for i := range outline {
+		next := outline[(i+1)%len(outline)]
+		verts := []vector.Vector3{
+			vector.NewVector3(center.X(), height, center.Y()),
+			vector.NewVector3(next.X(), height, next.Y()),
+			vector.NewVector3(outline[i].X(), height, outline[i].Y()),
+		}
+		poly, err := mesh.NewPolygon(verts, verts)
+		if err != nil {
+			return nil, err
+		}
+		polys[i] = poly
+	}
+	return polys, nil
+}
+
+func TestDoubleSidedMedalIsClosed(t *testing.T) {
+	// Lopsided so a reverse that isn't mirrored properly can't line up
+	outline := shapedBody([]vector.Vector2{
+		vector.NewVector2(1, 0),
+		vector.NewVector2(.2, .9),
+		vector.NewVector2(-.8, .6),
+		vector.NewVector2(-.7, -.5),
+		vector.NewVector2(.4, -.8),
+	})
+	side := sineProfile(defaultBulge, 4, .3)
+
+	body, rim, reverseRim, err := makeMedalionParts(outline, side, .1, .05, fanFace, &medalReverse{
+		impression: .08,
+		border:     .1,
+		face:       fanFace,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	faces := append(append(body.GetFaces(), rim.GetFaces()...), reverseRim.GetFaces()...)
+	assertClosedManifold(t, faces)
+
+	// The reverse rim runs from the bottom of the side up to the reverse
+	// face
+	assert.Len(t, reverseRim.GetFaces(), 5*2*2)
+	for _, face := range reverseRim.GetFaces() {
+		for _, v := range face.GetVertices() {
+			assert.GreaterOrEqual(t, v.Y(), 0.)
+			assert.LessOrEqual(t, v.Y(), .08+1e-9)
+		}
+	}
+
+	// The reverse face sits inside its own border, facing down
+	corners := make(map[[2]float64]bool)
+	for _, p := range outline(-.1) {
+		corners[[2]float64{math.Round(p.X() * 1e9), math.Round(p.Y() * 1e9)}] = true
+	}
+	reverseFaces := 0
+	for _, face := range body.GetFaces() {
+		v := face.GetVertices()
+		if v[0].Y() != .08 || v[1].Y() != .08 || v[2].Y() != .08 {
+			continue
+		}
+		reverseFaces++
+		assert.Less(t, faceNormal(face).Y(), 0.)
+		for _, corner := range v[1:] {
+			assert.True(t, corners[[2]float64{math.Round(corner.X() * 1e9), math.Round(corner.Z() * 1e9)}], "reverse face point %v isn't on the outline", corner)
+		}
+	}
+	assert.Equal(t, 5, reverseFaces)
+}
+
+func TestTurnOverReadsFromBelow(t *testing.T) {
+	// Looking down at a face X points left, so text reads towards -X. Once
+	// turned over and looked at from below it still reads from left to
+	// right, which is now towards +X, and stays the same way up.
+	start := vector.NewVector3(.5, -.1, .2)
+	end := vector.NewVector3(-.5, -.1, .2)
+	verts := []vector.Vector3{start, end, vector.NewVector3(0, -.1, .6)}
+	poly, _ := mesh.NewPolygon(verts, verts)
+	model, _ := mesh.NewModel([]mesh.Polygon{poly})
+
+	turned := turnOver(model).GetFaces()[0].GetVertices()
+	assert.InDelta(t, -.5, turned[0].X(), 1e-9)
+	assert.InDelta(t, .5, turned[1].X(), 1e-9)
+	assert.InDelta(t, .1, turned[0].Y(), 1e-9)
+	assert.InDelta(t, .2, turned[0].Z(), 1e-9)
+	assert.InDelta(t, .6, turned[2].Z(), 1e-9)
+
+	assert.Greater(t, faceNormal(poly).Y(), 0.)
+	assert.Less(t, faceNormal(turnOver(model).GetFaces()[0]).Y(), 0.)
+}
+
+func TestCarveRejectsCutoutsCrossingTheOutline(t *testing.T) {
+	star := starOutline(5, 1, .5)
+
+	// Every corner sits inside the star, but the edge between the two points
+	// cuts across the notch between them
+	cutout, err := mesh.NewShape([]vector.Vector2{
+		vector.Vector2Zero(),
+		star[0].MultByConstant(.9),
+		star[2].MultByConstant(.9),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = carve(star, []Outline{{Outer: cutout}}, 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "crosses the outline being carved")
+	}
+}
+
+func TestCheckBorderCatchesNarrowShapes(t *testing.T) {
+	// Two squares joined by a neck 1 wide
+	p := vector.NewVector2
+	dumbbell := shapedBody([]vector.Vector2{
+		p(0, 0), p(4, 0), p(4, 1), p(6, 1), p(6, 0), p(10, 0),
+		p(10, 3), p(6, 3), p(6, 2), p(4, 2), p(4, 3), p(0, 3),
+	})
+	assert.NoError(t, checkBorder(dumbbell, .4))
+
+	err := checkBorder(dumbbell, .6)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rim border 0.6 is too wide for the medal's shape")
+	}
+}
+
+func TestCustomOutlineFollowsTheQuality(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "round.svg")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`<svg viewBox="0 0 100 100"><circle cx="50" cy="50" r="50"/></svg>`), 0644))
+
+	body := bodySpec{Shape: "custom", Path: path, Radius: 2}
+	assets := newMedalAssets()
+	for _, tolerance := range []float64{.01, .0001} {
+		outline, err := medalOutline(body, qualitySpec{Tolerance: tolerance, MaxAngle: 90}, nil, assets)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// Tolerance is in the medal's units, however big the SVG is. The
+		// points sit on a circle, centered wherever the average of them is.
+		points := outline(0)
+		center := vector.Vector2Zero()
+		for _, p := range points {
+			center = center.Add(p.MultByConstant(1 / float64(len(points))))
+		}
+		radius := points[0].Distance(center)
+		for i, p := range points {
+			middle := p.Add(points[(i+1)%len(points)]).MultByConstant(.5)
+			assert.LessOrEqual(t, radius-middle.Distance(center), tolerance+1e-9)
+		}
+	}
+}
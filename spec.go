@@ -0,0 +1,1072 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultFont = "./sample.ttf"
+
+// medalSpec describes an entire medal design. Specs are written as YAML or
+// JSON (which YAML is a superset of) so designs can be checked in without
+// touching any Go code.
+type medalSpec struct {
+	Body    bodySpec     `yaml:"body"`
+	Text    []textSpec   `yaml:"text"`
+	Logo    *logoSpec    `yaml:"logo"`
+	Relief  *reliefSpec  `yaml:"relief"`
+	Reverse *reverseSpec `yaml:"reverse"`
+	Quality qualitySpec  `yaml:"quality"`
+	Output  outputSpec   `yaml:"output"`
+}
+
+// reverseSpec is the design on the back of a double sided medal, which has
+// its own rim and face sunk into the bottom of the body. It's laid out as if
+// it were the front, so it reads the right way round once the medal is
+// turned over side to side. Medals without one have a flat back.
+type reverseSpec struct {
+	// How far the reverse face sits in from the bottom of the rim. Defaults
+	// to the body's impression.
+	Impression float64 `yaml:"impression"`
+
+	// Rim around the reverse face, which defaults to the body's border and
+	// rim material
+	Rim rimSpec `yaml:"rim"`
+
+	Text []textSpec `yaml:"text"`
+
+	// Logo on the reverse, which has to be an SVG
+	Logo *logoSpec `yaml:"logo"`
+
+	Relief *reliefSpec `yaml:"relief"`
+}
+
+// qualitySpec is how finely every curve of the medal is broken into
+// straight lines, so big medals stay smooth without small ones being made
+// of more triangles than a printer can show.
+type qualitySpec struct {
+	// Tolerance is the furthest a line can stray from the curve it stands
+	// in for, in the units the medal is modelled in
+	Tolerance float64 `yaml:"tolerance"`
+
+	// MaxAngle in degrees is the most a curve can turn over a single line
+	MaxAngle float64 `yaml:"maxAngle"`
+}
+
+// medalShapes are all the shapes a medal's body can be.
+var medalShapes = []string{"circle", "polygon", "star", "shield", "custom"}
+
+// bodySpec is the medallion everything else sits on.
+type bodySpec struct {
+	// Shape of the medal's outline, one of medalShapes
+	Shape string `yaml:"shape"`
+
+	// Radius of the medal before the side starts bulging out. Polygons and
+	// stars have their corners this far from the center, shields are twice
+	// as tall as it, and custom shapes are scaled so their furthest point
+	// is this far away.
+	Radius float64 `yaml:"radius"`
+
+	// Sides of a polygon shaped medal
+	Sides int `yaml:"sides"`
+
+	// Points of a star shaped medal, and how far the corners between them
+	// sit from the center. Defaults to half the radius.
+	Points      int     `yaml:"points"`
+	InnerRadius float64 `yaml:"innerRadius"`
+
+	// Path to an SVG whose largest shape is the outline of a custom shaped
+	// medal
+	Path string `yaml:"path"`
+
+	// Thickness of the medal from the bottom plate to the top of the rim
+	Thickness float64 `yaml:"thickness"`
+
+	// How far the design face sits below the rim
+	Impression float64 `yaml:"impression"`
+
+	Rim rimSpec `yaml:"rim"`
+
+	Edge edgeSpec `yaml:"edge"`
+
+	Material string `yaml:"material"`
+}
+
+// edgeSpec is the shape of the medal's side seen edge on.
+type edgeSpec struct {
+	// Profile is the name of one of the edgeProfiles
+	Profile string `yaml:"profile"`
+
+	// Size is how far a sine bulges out, how far an ogee bows out and curls
+	// back in, or how far a chamfer, round-over or stepped edge cuts in.
+	// Defaults to a size that suits the profile and the rims.
+	Size float64 `yaml:"size"`
+
+	// Resolution is how many segments make up each curve of the profile.
+	// Defaults to as many as the quality needs.
+	Resolution int `yaml:"resolution"`
+
+	// Steps of a stepped edge
+	Steps int `yaml:"steps"`
+
+	// Points a spline edge runs through from the bottom of the side to the
+	// top, each as how far the side sticks out past the outline and how
+	// far up the side it is from 0 at the bottom to 1 at the top
+	Points [][2]float64 `yaml:"points"`
+}
+
+// rimSpec is the raised border around the design face.
+type rimSpec struct {
+	// Border is how wide the rim is
+	Border float64 `yaml:"border"`
+
+	// Material of the rim, which is the body's material when left empty
+	Material string `yaml:"material"`
+}
+
+// textSpec is a single piece of text raised out of or cut into the design
+// face.
+type textSpec struct {
+	Text string `yaml:"text"`
+
+	// Style is either "emboss" to raise the text above the design face or
+	// "engrave" to cut it into the face
+	Style string `yaml:"style"`
+
+	// Depth is how far embossed text rises above the face, or how far
+	// engraved text sinks below it. Defaults to the body's impression.
+	Depth float64 `yaml:"depth"`
+
+	// Font is the path to a TrueType font file, or to a TrueType
+	// collection followed by the index of the font in it like
+	// "fonts/Noto.ttc#2". Defaults to sample.ttf next to the spec.
+	Font string `yaml:"font"`
+
+	// FallbackFonts are tried in order for any character Font has no
+	// glyph for
+	FallbackFonts []string `yaml:"fallbackFonts"`
+
+	// Layout is the name of one of the textLayouts
+	Layout string `yaml:"layout"`
+
+	// Radius of the arc the baseline of arced text sits on, or of the
+	// circle a block of text is shrunk to fit inside. Defaults to fitting
+	// the text just inside the rim.
+	Radius float64 `yaml:"radius"`
+
+	// Angle in degrees arced text is centered on, counter clockwise from
+	// the right hand side of the face. Defaults to the top of the face for
+	// top-arc and the bottom for bottom-arc.
+	Angle *float64 `yaml:"angle"`
+
+	// LetterSpacing is added between every letter on top of the font's own
+	// spacing
+	LetterSpacing float64 `yaml:"letterSpacing"`
+
+	// Align is how the lines of a block of text line up, one of the
+	// textAligns
+	Align string `yaml:"align"`
+
+	// LineHeight is how far apart the lines of a block of text are, as a
+	// multiple of its scale
+	LineHeight float64 `yaml:"lineHeight"`
+
+	// Width is how wide the lines of a block of text can get before
+	// they're wrapped. Defaults to the width of the circle the block fits
+	// inside.
+	Width float64 `yaml:"width"`
+
+	Scale float64 `yaml:"scale"`
+
+	// CurveTolerance is the furthest a flattened letter curve can stray
+	// from the real curve, in the units of the text before it's scaled.
+	// Defaults to the quality's tolerance.
+	CurveTolerance float64 `yaml:"curveTolerance"`
+
+	Material string `yaml:"material"`
+}
+
+// logoSpec is an OBJ or STL mesh, or the filled shapes of an SVG, placed on
+// the design face.
+type logoSpec struct {
+	Path string `yaml:"path"`
+
+	// Scale is applied evenly to the logo mesh as it's read from disk. SVG
+	// logos are instead scaled so their longest side is this long.
+	Scale float64 `yaml:"scale"`
+
+	// Height is where the center of a mesh logo sits above the bottom plate
+	Height float64 `yaml:"height"`
+
+	// Style is either "emboss" to raise an SVG logo above the design face or
+	// "engrave" to cut it into the face. Mesh logos can only be embossed.
+	Style string `yaml:"style"`
+
+	// Depth is how far an SVG logo rises above or sinks below the face.
+	// Defaults to the body's impression.
+	Depth float64 `yaml:"depth"`
+
+	// Rotation in degrees counter clockwise looking down at the face
+	Rotation float64 `yaml:"rotation"`
+
+	// Offset moves the logo across the face from the center
+	Offset [2]float64 `yaml:"offset"`
+
+	Material string `yaml:"material"`
+}
+
+// reliefSpec is a PNG or JPEG raised out of the design face as a
+// bas-relief, with brighter pixels standing taller.
+type reliefSpec struct {
+	Path string `yaml:"path"`
+
+	// Height the brightest pixels stand above the face. Defaults to the
+	// body's impression so the relief never rises past the rim.
+	Height float64 `yaml:"height"`
+
+	// Smoothing blurs the image this many pixels before it's raised, where
+	// 0 leaves it sharp. Defaults to the smoothing of defaultReliefSpec.
+	Smoothing float64 `yaml:"smoothing"`
+
+	// MaxTriangles is the most triangles the relief can be made of
+	MaxTriangles int `yaml:"maxTriangles"`
+
+	// Invert raises dark pixels instead of bright ones
+	Invert bool `yaml:"invert"`
+}
+
+// UnmarshalYAML decodes text over the default font, so a font left out of
+// the spec is found next to it while an empty one is reported.
+func (t *textSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain textSpec
+	decoded := plain{Font: defaultFont}
+	if err := node.Decode(&decoded); err != nil {
+		return err
+	}
+	*t = textSpec(decoded)
+	return nil
+}
+
+// UnmarshalYAML decodes a relief over the defaults of defaultReliefSpec,
+// so anything written in the spec, including a smoothing of 0, is kept.
+func (r *reliefSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain reliefSpec
+	decoded := plain(defaultReliefSpec())
+	if err := node.Decode(&decoded); err != nil {
+		return err
+	}
+	*r = reliefSpec(decoded)
+	return nil
+}
+
+// outputSpec is where and how the medal is saved.
+type outputSpec struct {
+	Path string `yaml:"path"`
+
+	// Format of the file written, derived from the path's extension when
+	// left empty. One of outputFormats.
+	Format string `yaml:"format"`
+
+	// Units the medal is modelled in, recorded in formats that support it
+	Units string `yaml:"units"`
+
+	// MaterialLibrary is an MTL file referenced by the output that defines
+	// the materials used throughout the spec
+	MaterialLibrary string `yaml:"materialLibrary"`
+}
+
+// outputFormats are every format a medal can be saved as. STL is binary
+// unless "stl-ascii" is asked for.
+var outputFormats = []string{"obj", "stl", "stl-ascii", "gltf", "glb"}
+
+// outputUnits are the units a medal can be modelled in.
+var outputUnits = []string{"mm", "cm", "m", "in"}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatExtension is the file extension used for a format.
+func formatExtension(format string) string {
+	if format == "stl-ascii" {
+		return ".stl"
+	}
+	return "." + format
+}
+
+func defaultTextSpec() textSpec {
+	return textSpec{
+		Font:       defaultFont,
+		Style:      "emboss",
+		Layout:     "top-arc",
+		Align:      "center",
+		LineHeight: 1.2,
+		Scale:      .4,
+	}
+}
+
+func defaultLogoSpec() logoSpec {
+	return logoSpec{
+		Scale:  1.0 / 90.0,
+		Height: .22,
+		Style:  "emboss",
+	}
+}
+
+// defaultSVGLogoScale is how long the longest side of an SVG logo is when
+// the spec doesn't say.
+const defaultSVGLogoScale = .5
+
+// isSVG is whether the logo is read from the shapes of an SVG rather
+// than a mesh.
+func (l logoSpec) isSVG() bool {
+	return strings.ToLower(filepath.Ext(l.Path)) == ".svg"
+}
+
+// minReliefTriangles is the fewest triangles a relief can be made of and
+// still have room for any detail inside the face's outline.
+const minReliefTriangles = 500
+
+func defaultReliefSpec() reliefSpec {
+	return reliefSpec{
+		Smoothing:    1,
+		MaxTriangles: 20000,
+	}
+}
+
+func defaultMedalSpec() medalSpec {
+	return medalSpec{
+		Body: bodySpec{
+			Shape:      "circle",
+			Radius:     1.0,
+			Sides:      6,
+			Points:     5,
+			Thickness:  0.3,
+			Impression: 0.1,
+			Rim:        rimSpec{Border: 0.05},
+			Edge: edgeSpec{
+				Profile: "sine",
+				Steps:   3,
+			},
+		},
+		Quality: qualitySpec{
+			Tolerance: .005,
+			MaxAngle:  360. / circleSides,
+		},
+		Output: outputSpec{
+			Path:  "out.obj",
+			Units: "mm",
+		},
+	}
+}
+
+// applyDefaults fills in everything left unset after decoding a spec.
+func (s *medalSpec) applyDefaults() {
+	if s.Body.Shape == "star" && s.Body.InnerRadius == 0 {
+		s.Body.InnerRadius = s.Body.Radius / 2
+	}
+
+	applyDesignDefaults(s.Text, s.Logo, s.Relief, s.Body.Impression, s.Quality)
+
+	if s.Reverse != nil {
+		if s.Reverse.Impression == 0 {
+			s.Reverse.Impression = s.Body.Impression
+		}
+		if s.Reverse.Rim.Border == 0 {
+			s.Reverse.Rim.Border = s.Body.Rim.Border
+		}
+		if s.Reverse.Rim.Material == "" {
+			s.Reverse.Rim.Material = s.Body.Rim.Material
+		}
+		applyDesignDefaults(s.Reverse.Text, s.Reverse.Logo, s.Reverse.Relief, s.Reverse.Impression, s.Quality)
+	}
+
+	// Edges that cut in stay inside the narrowest rim and half the
+	// thickness, so the top and bottom of the side never pass them
+	if profile, ok := edgeProfiles[s.Body.Edge.Profile]; ok && profile.defaultSize != nil && s.Body.Edge.Size == 0 {
+		inset := math.Min(s.Body.Rim.Border, s.Body.Thickness/2)
+		if s.Reverse != nil {
+			inset = math.Min(inset, s.Reverse.Rim.Border)
+		}
+		s.Body.Edge.Size = profile.defaultSize(inset)
+	}
+
+	if s.Output.Format == "" {
+		s.Output.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(s.Output.Path)), ".")
+	}
+	if s.Output.Units == "" {
+		s.Output.Units = defaultMedalSpec().Output.Units
+	}
+}
+
+// applyDesignDefaults fills in everything left unset in the design of one
+// face of the medal, where depths and heights default to the face's
+// impression.
+func applyDesignDefaults(texts []textSpec, logo *logoSpec, relief *reliefSpec, impression float64, quality qualitySpec) {
+	for i := range texts {
+		defaults := defaultTextSpec()
+		if texts[i].Style == "" {
+			texts[i].Style = defaults.Style
+		}
+		if texts[i].Depth == 0 {
+			texts[i].Depth = impression
+		}
+		if texts[i].Layout == "" {
+			texts[i].Layout = defaults.Layout
+		}
+		if texts[i].Align == "" {
+			texts[i].Align = defaults.Align
+		}
+		if texts[i].LineHeight == 0 {
+			texts[i].LineHeight = defaults.LineHeight
+		}
+		if texts[i].Scale == 0 {
+			texts[i].Scale = defaults.Scale
+		}
+		if texts[i].CurveTolerance == 0 && quality.Tolerance > 0 {
+			texts[i].CurveTolerance = quality.curveTolerance(texts[i].Scale)
+		}
+	}
+
+	if logo != nil {
+		defaults := defaultLogoSpec()
+		if logo.Scale == 0 {
+			logo.Scale = defaults.Scale
+			if logo.isSVG() {
+				logo.Scale = defaultSVGLogoScale
+			}
+		}
+		if logo.Height == 0 {
+			logo.Height = defaults.Height
+		}
+		if logo.Style == "" {
+			logo.Style = defaults.Style
+		}
+		if logo.Depth == 0 {
+			logo.Depth = impression
+		}
+	}
+
+	if relief != nil {
+		defaults := defaultReliefSpec()
+		if relief.Height == 0 {
+			relief.Height = impression
+		}
+		if relief.MaxTriangles == 0 {
+			relief.MaxTriangles = defaults.MaxTriangles
+		}
+	}
+}
+
+// resolvePaths makes every file referenced by the spec relative to dir, so
+// specs can refer to fonts and logos sitting next to them.
+func (s *medalSpec) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	s.Body.Path = resolve(s.Body.Path)
+
+	resolveDesign := func(texts []textSpec, logo *logoSpec, relief *reliefSpec) {
+		for i := range texts {
+			texts[i].Font = resolve(texts[i].Font)
+			for f := range texts[i].FallbackFonts {
+				texts[i].FallbackFonts[f] = resolve(texts[i].FallbackFonts[f])
+			}
+		}
+
+		if logo != nil {
+			logo.Path = resolve(logo.Path)
+		}
+
+		if relief != nil {
+			relief.Path = resolve(relief.Path)
+		}
+	}
+
+	resolveDesign(s.Text, s.Logo, s.Relief)
+	if s.Reverse != nil {
+		resolveDesign(s.Reverse.Text, s.Reverse.Logo, s.Reverse.Relief)
+	}
+}
+
+// specError is a problem found with a medal spec, along with the line of
+// the spec file it came from when known.
+type specError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e specError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// specErrors is every problem found while validating a spec.
+type specErrors []specError
+
+func (e specErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// specNodeLine finds the line in the document the field at path was
+// defined on. Sequence elements are addressed by their index. Returns the
+// line of the deepest node found, or 0 when there's no document.
+func specNodeLine(root *yaml.Node, path ...string) int {
+	if root == nil {
+		return 0
+	}
+
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+	for _, key := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(key)
+			if err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line = next.Line
+			}
+		}
+
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
+
+// specFieldName formats a path into the document the way it's written in
+// error messages, like text[0].layout
+func specFieldName(path ...string) string {
+	name := ""
+	for _, key := range path {
+		if _, err := strconv.Atoi(key); err == nil {
+			name += "[" + key + "]"
+		} else if name == "" {
+			name = key
+		} else {
+			name += "." + key
+		}
+	}
+	return name
+}
+
+// checkSpecFields walks the document and reports every key that doesn't
+// belong to the struct it's decoded into.
+func checkSpecFields(node *yaml.Node, t reflect.Type, path string) specErrors {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		if t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode {
+			errs := specErrors{}
+			for i, element := range node.Content {
+				errs = append(errs, checkSpecFields(element, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return errs
+		}
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	errs := specErrors{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+
+		var field *reflect.StructField
+		for f := 0; f < t.NumField(); f++ {
+			candidate := t.Field(f)
+			if strings.Split(candidate.Tag.Get("yaml"), ",")[0] == key.Value {
+				field = &candidate
+				break
+			}
+		}
+
+		fieldPath := key.Value
+		if path != "" {
+			fieldPath = path + "." + key.Value
+		}
+
+		if field == nil {
+			errs = append(errs, specError{Line: key.Line, Field: fieldPath, Message: "unknown field"})
+			continue
+		}
+
+		errs = append(errs, checkSpecFields(node.Content[i+1], field.Type, fieldPath)...)
+	}
+	return errs
+}
+
+// edge is the profile of the medal's side, with as many segments in each
+// curve as the quality needs when the spec doesn't say.
+func (s medalSpec) edge() edgeSpec {
+	edge := s.Body.Edge
+	if edge.Resolution == 0 {
+		edge.Resolution = s.Quality.profileResolution(edgeProfiles[edge.Profile], edge, s.Body.Thickness)
+	}
+	return edge
+}
+
+// valid is whether the quality can be used to break curves up.
+func (q qualitySpec) valid() bool {
+	return q.Tolerance > 0 && q.MaxAngle > 0 && q.MaxAngle < 180
+}
+
+// validateEdge checks the profile of the medal's side, reporting every
+// problem found.
+func (s medalSpec) validateEdge(report func(message string, path ...string)) {
+	body := s.Body
+	edge := body.Edge
+
+	profile, ok := edgeProfiles[edge.Profile]
+	if !ok {
+		report(fmt.Sprintf("unknown profile %q, must be one of %s", edge.Profile, strings.Join(edgeProfileNames(), ", ")), "body", "edge", "profile")
+		return
+	}
+
+	valid := true
+	if edge.Resolution < 0 {
+		report(fmt.Sprintf("can't be negative, got %d", edge.Resolution), "body", "edge", "resolution")
+		valid = false
+	}
+
+	switch edge.Profile {
+	case "chamfer", "round-over":
+		if edge.Size <= 0 || edge.Size >= body.Thickness/2 {
+			report(fmt.Sprintf("must be within (0, thickness / 2), got %g", edge.Size), "body", "edge", "size")
+			valid = false
+		}
+	case "ogee":
+		if edge.Size <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", edge.Size), "body", "edge", "size")
+			valid = false
+		}
+	case "stepped":
+		if edge.Size <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", edge.Size), "body", "edge", "size")
+			valid = false
+		}
+		if edge.Steps < 2 {
+			report(fmt.Sprintf("must be at least 2, got %d", edge.Steps), "body", "edge", "steps")
+			valid = false
+		}
+	case "spline":
+		if len(edge.Points) < 2 {
+			report(fmt.Sprintf("needs at least 2 points, got %d", len(edge.Points)), "body", "edge", "points")
+			valid = false
+			break
+		}
+		if edge.Points[0][1] != 0 || edge.Points[len(edge.Points)-1][1] != 1 {
+			report("must run from a height of 0 at the bottom to 1 at the top", "body", "edge", "points")
+			valid = false
+		}
+		for i := 1; i < len(edge.Points); i++ {
+			if edge.Points[i][1] <= edge.Points[i-1][1] {
+				report("each point must be higher than the one before it", "body", "edge", "points", strconv.Itoa(i))
+				valid = false
+			}
+		}
+	}
+
+	if !valid || body.Thickness <= 0 || !s.Quality.valid() {
+		return
+	}
+
+	// The rim starts where the side ends, so the side can't end inside it
+	side := profile.points(s.edge(), body.Thickness)
+	if top := side[len(side)-1].X(); top <= -body.Rim.Border {
+		report(fmt.Sprintf("the top of the side is %g inside the outline, past the rim's border", -top), "body", "edge")
+	}
+	if bottom := side[0].X(); s.Reverse != nil && bottom <= -s.Reverse.Rim.Border {
+		report(fmt.Sprintf("the bottom of the side is %g inside the outline, past the reverse rim's border", -bottom), "body", "edge")
+	}
+
+	// Splines overshoot between points spaced unevenly, which can turn the
+	// side back down on itself
+	if edge.Profile == "spline" {
+		resolution := s.edge().Resolution
+		for i := 1; i < len(side); i++ {
+			if side[i].Y() <= side[i-1].Y() {
+				segment := (i - 1) / resolution
+				report(fmt.Sprintf("the curve between points %d and %d dips back down, space the points more evenly", segment, segment+1), "body", "edge", "points")
+				break
+			}
+		}
+	}
+}
+
+// validate checks every value in the spec, using the document the spec was
+// decoded from (if any) to point out which line each problem is on.
+func (s medalSpec) validate(root *yaml.Node) error {
+	errs := specErrors{}
+	report := func(message string, path ...string) {
+		errs = append(errs, specError{
+			Line:    specNodeLine(root, path...),
+			Field:   specFieldName(path...),
+			Message: message,
+		})
+	}
+
+	body := s.Body
+	switch body.Shape {
+	case "circle", "shield":
+	case "polygon":
+		if body.Sides < 3 {
+			report(fmt.Sprintf("must be at least 3, got %d", body.Sides), "body", "sides")
+		}
+	case "star":
+		if body.Points < 3 {
+			report(fmt.Sprintf("must be at least 3, got %d", body.Points), "body", "points")
+		}
+		if body.InnerRadius <= body.Rim.Border || body.InnerRadius >= body.Radius {
+			report(fmt.Sprintf("must be within (rim border, radius), got %g", body.InnerRadius), "body", "innerRadius")
+		}
+	case "custom":
+		if body.Path == "" {
+			report("path is required", "body", "path")
+		} else if strings.ToLower(filepath.Ext(body.Path)) != ".svg" {
+			report(fmt.Sprintf("unsupported shape %s, must be an SVG", body.Path), "body", "path")
+		}
+	default:
+		report(fmt.Sprintf("unsupported shape %q, must be one of %s", body.Shape, strings.Join(medalShapes, ", ")), "body", "shape")
+	}
+	if body.Radius <= 0 {
+		report(fmt.Sprintf("must be greater than 0, got %g", body.Radius), "body", "radius")
+	}
+	if body.Thickness <= 0 {
+		report(fmt.Sprintf("must be greater than 0, got %g", body.Thickness), "body", "thickness")
+	}
+	if body.Impression < 0 || body.Impression >= body.Thickness {
+		report(fmt.Sprintf("must be within [0, thickness), got %g", body.Impression), "body", "impression")
+	}
+	if body.Rim.Border < 0 || body.Rim.Border >= body.Radius {
+		report(fmt.Sprintf("must be within [0, radius), got %g", body.Rim.Border), "body", "rim", "border")
+	}
+	if s.Quality.Tolerance <= 0 {
+		report(fmt.Sprintf("must be greater than 0, got %g", s.Quality.Tolerance), "quality", "tolerance")
+	}
+	if s.Quality.MaxAngle <= 0 || s.Quality.MaxAngle >= 180 {
+		report(fmt.Sprintf("must be within (0, 180), got %g", s.Quality.MaxAngle), "quality", "maxAngle")
+	}
+	s.validateEdge(report)
+
+	room := body.Thickness - body.Impression
+	front := faceLimits{
+		radius:     body.Radius - body.Rim.Border,
+		impression: body.Impression,
+		depth:      room,
+		depthName:  "thickness - impression",
+	}
+	if s.Reverse != nil {
+		room -= s.Reverse.Impression
+		front.depth = room
+		front.depthName = "thickness - impression - reverse impression"
+	}
+	validateDesign(s.Text, s.Logo, s.Relief, front, report)
+
+	if reverse := s.Reverse; reverse != nil {
+		if reverse.Impression <= 0 || reverse.Impression >= body.Thickness-body.Impression {
+			report(fmt.Sprintf("must be within (0, thickness - impression), got %g", reverse.Impression), "reverse", "impression")
+		}
+		if reverse.Rim.Border < 0 || reverse.Rim.Border >= body.Radius {
+			report(fmt.Sprintf("must be within [0, radius), got %g", reverse.Rim.Border), "reverse", "rim", "border")
+		}
+		if reverse.Logo != nil && reverse.Logo.Path != "" && !reverse.Logo.isSVG() {
+			report("only SVG logos can go on the reverse", "reverse", "logo", "path")
+		}
+
+		validateDesign(reverse.Text, reverse.Logo, reverse.Relief, faceLimits{
+			radius:     body.Radius - reverse.Rim.Border,
+			impression: reverse.Impression,
+			depth:      room,
+			depthName:  "thickness - impression - reverse impression",
+		}, report, "reverse")
+
+		// Engravings on each side sink towards each other, and can't meet in
+		// the middle
+		frontDepth := deepestEngraving(s.Text, s.Logo)
+		reverseDepth := deepestEngraving(reverse.Text, reverse.Logo)
+		if room > 0 && frontDepth < room && reverseDepth < room && frontDepth+reverseDepth >= room {
+			report(fmt.Sprintf("engravings on the front and reverse are %g deep between them, must be less than thickness - impression - reverse impression", frontDepth+reverseDepth), "reverse")
+		}
+	}
+
+	if s.Output.Path == "" {
+		report("path is required", "output", "path")
+	}
+	if !containsString(outputFormats, s.Output.Format) {
+		report(fmt.Sprintf("unsupported format %q, must be one of %s", s.Output.Format, strings.Join(outputFormats, ", ")), "output", "format")
+	}
+	if !containsString(outputUnits, s.Output.Units) {
+		report(fmt.Sprintf("unsupported units %q, must be one of %s", s.Output.Units, strings.Join(outputUnits, ", ")), "output", "units")
+	}
+
+	// Material libraries are found next to the output, and every material
+	// used needs to be in it. OBJ files only reference the library, so it's
+	// allowed to not exist yet.
+	if s.Output.MaterialLibrary != "" {
+		libraryPath := filepath.Join(filepath.Dir(s.Output.Path), s.Output.MaterialLibrary)
+		library, err := loadMTL(libraryPath)
+		switch {
+		case err == nil:
+			check := func(material string, path ...string) {
+				if _, ok := library[material]; material != "" && !ok {
+					report(fmt.Sprintf("material %q isn't defined in %s", material, libraryPath), path...)
+				}
+			}
+			check(body.Material, "body", "material")
+			check(body.Rim.Material, "body", "rim", "material")
+			for i, text := range s.Text {
+				check(text.Material, "text", strconv.Itoa(i), "material")
+			}
+			if s.Logo != nil {
+				check(s.Logo.Material, "logo", "material")
+			}
+			if s.Reverse != nil {
+				check(s.Reverse.Rim.Material, "reverse", "rim", "material")
+				for i, text := range s.Reverse.Text {
+					check(text.Material, "reverse", "text", strconv.Itoa(i), "material")
+				}
+				if s.Reverse.Logo != nil {
+					check(s.Reverse.Logo.Material, "reverse", "logo", "material")
+				}
+			}
+
+		case os.IsNotExist(err) && s.Output.Format == "obj":
+
+		default:
+			report(err.Error(), "output", "materialLibrary")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// faceLimits are how far the design on one face of the medal can reach.
+type faceLimits struct {
+	// radius text can be laid out at, inside the face's rim
+	radius float64
+
+	// impression of the face below its rim, which a relief can't rise past
+	impression float64
+
+	// depth engravings have to stay within, along with how it's worked out
+	// to explain it
+	depth     float64
+	depthName string
+}
+
+// validateDesign checks the text, logo and relief on one face of the medal,
+// reporting problems under the path of the face.
+func validateDesign(texts []textSpec, logo *logoSpec, relief *reliefSpec, face faceLimits, report func(message string, path ...string), prefix ...string) {
+	at := func(path ...string) []string {
+		return append(append([]string{}, prefix...), path...)
+	}
+
+	for i, text := range texts {
+		index := strconv.Itoa(i)
+		if strings.TrimSpace(text.Text) == "" {
+			report("text is required", at("text", index, "text")...)
+		}
+		if _, ok := textLayouts[text.Layout]; !ok {
+			report(fmt.Sprintf("unknown layout %q, must be one of %s", text.Layout, strings.Join(textLayoutNames(), ", ")), at("text", index, "layout")...)
+		}
+		switch text.Style {
+		case "emboss":
+			if text.Depth <= 0 {
+				report(fmt.Sprintf("must be greater than 0, got %g", text.Depth), at("text", index, "depth")...)
+			}
+		case "engrave":
+			if relief != nil {
+				report("can't engrave into a relief", at("text", index, "style")...)
+			} else if text.Depth <= 0 || text.Depth >= face.depth {
+				report(fmt.Sprintf("must be within (0, %s), got %g", face.depthName, text.Depth), at("text", index, "depth")...)
+			}
+		default:
+			report(fmt.Sprintf("unknown style %q, must be \"emboss\" or \"engrave\"", text.Style), at("text", index, "style")...)
+		}
+		if text.Scale <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", text.Scale), at("text", index, "scale")...)
+		}
+		if text.Radius < 0 || text.Radius >= face.radius {
+			report(fmt.Sprintf("must be within [0, radius - rim border), got %g", text.Radius), at("text", index, "radius")...)
+		}
+		if _, ok := textAligns[text.Align]; !ok {
+			report(fmt.Sprintf("unknown alignment %q, must be one of %s", text.Align, strings.Join(textAlignNames(), ", ")), at("text", index, "align")...)
+		}
+		if text.LineHeight <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", text.LineHeight), at("text", index, "lineHeight")...)
+		}
+		if text.Width < 0 {
+			report(fmt.Sprintf("can't be negative, got %g", text.Width), at("text", index, "width")...)
+		}
+		if text.CurveTolerance <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", text.CurveTolerance), at("text", index, "curveTolerance")...)
+		}
+		if text.Font == "" {
+			report("font path is required", at("text", index, "font")...)
+		} else if file, _ := splitFontPath(text.Font); !fileExists(file) {
+			report(missingFont(file), at("text", index, "font")...)
+		}
+		for f, fallback := range text.FallbackFonts {
+			if fallback == "" {
+				report("path is required", at("text", index, "fallbackFonts", strconv.Itoa(f))...)
+			} else if file, _ := splitFontPath(fallback); !fileExists(file) {
+				report(missingFont(file), at("text", index, "fallbackFonts", strconv.Itoa(f))...)
+			}
+		}
+	}
+
+	if logo != nil {
+		if logo.Path == "" {
+			report("path is required", at("logo", "path")...)
+		} else if ext := strings.ToLower(filepath.Ext(logo.Path)); ext != ".obj" && ext != ".stl" && ext != ".svg" {
+			report(fmt.Sprintf("unsupported logo %s, must be an OBJ, STL or SVG", logo.Path), at("logo", "path")...)
+		}
+		if logo.Scale <= 0 {
+			report(fmt.Sprintf("must be greater than 0, got %g", logo.Scale), at("logo", "scale")...)
+		}
+		switch logo.Style {
+		case "emboss":
+			if logo.Depth <= 0 {
+				report(fmt.Sprintf("must be greater than 0, got %g", logo.Depth), at("logo", "depth")...)
+			}
+		case "engrave":
+			if !logo.isSVG() {
+				report("only SVG logos can be engraved", at("logo", "style")...)
+			} else if relief != nil {
+				report("can't engrave into a relief", at("logo", "style")...)
+			} else if logo.Depth <= 0 || logo.Depth >= face.depth {
+				report(fmt.Sprintf("must be within (0, %s), got %g", face.depthName, logo.Depth), at("logo", "depth")...)
+			}
+		default:
+			report(fmt.Sprintf("unknown style %q, must be \"emboss\" or \"engrave\"", logo.Style), at("logo", "style")...)
+		}
+	}
+
+	if relief != nil {
+		if relief.Path == "" {
+			report("path is required", at("relief", "path")...)
+		} else if ext := strings.ToLower(filepath.Ext(relief.Path)); ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+			report(fmt.Sprintf("unsupported image %s, must be a PNG or JPEG", relief.Path), at("relief", "path")...)
+		}
+		if relief.Height <= 0 || relief.Height > face.impression {
+			report(fmt.Sprintf("must be within (0, impression], got %g", relief.Height), at("relief", "height")...)
+		}
+		if relief.Smoothing < 0 {
+			report(fmt.Sprintf("can't be negative, got %g", relief.Smoothing), at("relief", "smoothing")...)
+		}
+		if relief.MaxTriangles < minReliefTriangles {
+			report(fmt.Sprintf("must be at least %d, got %d", minReliefTriangles, relief.MaxTriangles), at("relief", "maxTriangles")...)
+		}
+	}
+}
+
+// deepestEngraving is how far the deepest engraved text or logo sinks into
+// its face, or 0 when nothing is engraved.
+func deepestEngraving(texts []textSpec, logo *logoSpec) float64 {
+	deepest := 0.
+	for _, text := range texts {
+		if text.Style == "engrave" {
+			deepest = math.Max(deepest, text.Depth)
+		}
+	}
+	if logo != nil && logo.Style == "engrave" {
+		deepest = math.Max(deepest, logo.Depth)
+	}
+	return deepest
+}
+
+// parseSpec reads a YAML or JSON medal spec. Everything left out of the
+// document falls back to the defaults of defaultMedalSpec, and relative
+// paths to fonts and logos are resolved against dir.
+func parseSpec(in io.Reader, dir string) (medalSpec, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return medalSpec{}, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return medalSpec{}, err
+	}
+
+	if len(root.Content) == 0 {
+		return medalSpec{}, specErrors{{Field: "spec", Message: "document is empty"}}
+	}
+
+	if errs := checkSpecFields(root.Content[0], reflect.TypeOf(medalSpec{}), ""); len(errs) > 0 {
+		return medalSpec{}, errs
+	}
+
+	spec := defaultMedalSpec()
+	if err := root.Decode(&spec); err != nil {
+		return medalSpec{}, err
+	}
+
+	spec.applyDefaults()
+	spec.resolvePaths(dir)
+	if err := spec.validate(&root); err != nil {
+		return medalSpec{}, err
+	}
+
+	return spec, nil
+}
+
+// loadSpec reads a medal spec from disk, resolving every path within it
+// relative to the spec file itself.
+func loadSpec(path string) (medalSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return medalSpec{}, err
+	}
+	defer f.Close()
+
+	spec, err := parseSpec(f, filepath.Dir(path))
+	if err != nil {
+		return medalSpec{}, fmt.Errorf("%s:\n%w", path, err)
+	}
+	return spec, nil
+}
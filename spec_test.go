@@ -0,0 +1,387 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSpecAppliesDefaults(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`
+body:
+  radius: 2
+text:
+  - text: Aleatha
+  - text: Singleton
+    layout: bottom-arc
+    material: wood
+output:
+  path: medal.obj
+`), ".")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2., spec.Body.Radius)
+	assert.Equal(t, 0.3, spec.Body.Thickness)
+	assert.Equal(t, "circle", spec.Body.Shape)
+	if assert.Len(t, spec.Text, 2) {
+		assert.Equal(t, "top-arc", spec.Text[0].Layout)
+		assert.Equal(t, "emboss", spec.Text[0].Style)
+		assert.Equal(t, 0.1, spec.Text[0].Depth)
+		assert.Equal(t, "sample.ttf", spec.Text[0].Font)
+		assert.Equal(t, "bottom-arc", spec.Text[1].Layout)
+		assert.Equal(t, "wood", spec.Text[1].Material)
+	}
+	assert.Nil(t, spec.Logo)
+	assert.Equal(t, "obj", spec.Output.Format)
+}
+
+func TestParseSpecAcceptsJSON(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`{
+	"body": {"radius": 1.5, "rim": {"border": 0.1}},
+	"logo": {"path": "logo.obj", "rotation": 90}
+}`), "designs")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, spec.Body.Radius)
+	assert.Equal(t, 0.1, spec.Body.Rim.Border)
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, "designs/logo.obj", spec.Logo.Path)
+		assert.Equal(t, 90., spec.Logo.Rotation)
+		assert.Equal(t, defaultLogoSpec().Scale, spec.Logo.Scale)
+	}
+}
+
+func TestParseSpecReportsUnknownFieldsWithLines(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`body:
+  radius: 2
+  colour: red
+text:
+  - text: Aleatha
+    size: 3
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: body.colour: unknown field")
+		assert.Contains(t, err.Error(), "line 6: text[0].size: unknown field")
+	}
+}
+
+func TestParseSpecReportsInvalidValuesWithLines(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`body:
+  radius: -1
+  thickness: 0.3
+  impression: 0.4
+text:
+  - text: Aleatha
+    layout: sideways
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: body.radius")
+		assert.Contains(t, err.Error(), "line 4: body.impression")
+		assert.Contains(t, err.Error(), "line 7: text[0].layout: unknown layout \"sideways\"")
+	}
+}
+
+func TestParseSpecChecksEngravingDepth(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`body:
+  thickness: 0.3
+  impression: 0.1
+text:
+  - text: Aleatha
+    style: engrave
+    depth: 0.2
+  - text: Singleton
+    style: stamp
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 7: text[0].depth: must be within (0, thickness - impression)")
+		assert.Contains(t, err.Error(), "line 9: text[1].style: unknown style \"stamp\"")
+	}
+}
+
+func TestParseSpecChecksMaterialsAgainstLibrary(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`body:
+  material: wood
+  rim:
+    material: marble
+output:
+  path: out.obj
+  materialLibrary: master.mtl
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 4: body.rim.material: material \"marble\" isn't defined in master.mtl")
+		assert.NotContains(t, err.Error(), "body.material")
+	}
+}
+
+func TestParseSpecDefaultsSVGLogos(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`body:
+  impression: 0.1
+logo:
+  path: emblem.svg
+  style: engrave
+`), ".")
+
+	// The emblem doesn't exist, but the defaults are filled in regardless
+	assert.NoError(t, err)
+	if assert.NotNil(t, spec.Logo) {
+		assert.Equal(t, defaultSVGLogoScale, spec.Logo.Scale)
+		assert.Equal(t, "engrave", spec.Logo.Style)
+		assert.Equal(t, 0.1, spec.Logo.Depth)
+	}
+}
+
+func TestParseSpecOnlyEngravesSVGLogos(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`logo:
+  path: logo.obj
+  style: engrave
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: logo.style: only SVG logos can be engraved")
+	}
+}
+
+func TestParseSpecChecksReliefs(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`body:
+  impression: 0.1
+relief:
+  path: portrait.png
+`), ".")
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, spec.Relief) {
+		assert.Equal(t, 0.1, spec.Relief.Height)
+		assert.Equal(t, defaultReliefSpec().MaxTriangles, spec.Relief.MaxTriangles)
+		assert.Equal(t, defaultReliefSpec().Smoothing, spec.Relief.Smoothing)
+	}
+
+	// Asking for no smoothing leaves the image sharp rather than bringing
+	// back the default
+	spec, err = parseSpec(strings.NewReader(`body:
+  impression: 0.1
+relief:
+  path: portrait.png
+  smoothing: 0
+`), ".")
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, spec.Relief) {
+		assert.Equal(t, 0., spec.Relief.Smoothing)
+	}
+
+	_, err = parseSpec(strings.NewReader(`body:
+  impression: 0.1
+text:
+  - text: Aleatha
+    style: engrave
+    depth: 0.05
+relief:
+  path: portrait.gif
+  height: 0.2
+`), ".")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 5: text[0].style: can't engrave into a relief")
+		assert.Contains(t, err.Error(), "line 8: relief.path: unsupported image portrait.gif, must be a PNG or JPEG")
+		assert.Contains(t, err.Error(), "line 9: relief.height: must be within (0, impression], got 0.2")
+	}
+}
+
+func TestParseSpecChecksFonts(t *testing.T) {
+	_, err := parseSpec(strings.NewReader(`text:
+  - text: Aleatha
+    font: missing.ttc#1
+    fallbackFonts:
+      - sample.ttf
+      - other.ttf
+  - text: Ward
+    font: ""
+  - text: Inkwell
+`), "fonts")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: text[0].font: unable to find font fonts/missing.ttc in ")
+		assert.Contains(t, err.Error(), "line 6: text[0].fallbackFonts[1]: unable to find font fonts/other.ttf in ")
+		assert.Contains(t, err.Error(), "line 8: text[1].font: font path is required")
+
+		// The default font is looked for next to the spec like any other
+		assert.Contains(t, err.Error(), "text[2].font: unable to find font fonts/sample.ttf in ")
+	}
+}
+
+func TestParseSpecChecksTextBlocks(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`text:
+  - text: "Employee of the Year\n2026"
+    layout: block
+`), ".")
+	assert.NoError(t, err)
+	if assert.Len(t, spec.Text, 1) {
+		assert.Equal(t, "center", spec.Text[0].Align)
+		assert.Equal(t, 1.2, spec.Text[0].LineHeight)
+	}
+
+	_, err = parseSpec(strings.NewReader(`text:
+  - text: Employee of the Year
+    layout: block
+    align: middle
+    lineHeight: -1
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `line 4: text[0].align: unknown alignment "middle", must be one of center, justify, left, right`)
+		assert.Contains(t, err.Error(), "line 5: text[0].lineHeight: must be greater than 0, got -1")
+	}
+}
+
+func TestParseSpecChecksShapes(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`body:
+  shape: star
+  radius: 2
+`), ".")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, spec.Body.Points)
+	assert.Equal(t, 1., spec.Body.InnerRadius)
+
+	_, err = parseSpec(strings.NewReader(`body:
+  shape: polygon
+  sides: 2
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: body.sides: must be at least 3, got 2")
+	}
+
+	_, err = parseSpec(strings.NewReader(`body:
+  shape: custom
+  path: shield.png
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3: body.path: unsupported shape shield.png, must be an SVG")
+	}
+
+	_, err = parseSpec(strings.NewReader(`body:
+  shape: oval
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `line 2: body.shape: unsupported shape "oval", must be one of circle, polygon, star, shield, custom`)
+	}
+}
+
+func TestParseSpecChecksEdges(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`body:
+  edge:
+    profile: chamfer
+    size: 0.03
+`), ".")
+	assert.NoError(t, err)
+
+	// Chamfers have no curves to break up
+	assert.Equal(t, 0, spec.Body.Edge.Resolution)
+	assert.Equal(t, 1, spec.edge().Resolution)
+
+	_, err = parseSpec(strings.NewReader(`body:
+  edge:
+    profile: chamfer
+    size: 0.08
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: body.edge: the top of the side is 0.08 inside the outline, past the rim's border")
+	}
+
+	_, err = parseSpec(strings.NewReader(`body:
+  edge:
+    profile: spline
+    points:
+      - [0, 0]
+      - [0.05, 0.6]
+      - [0, 0.5]
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 4: body.edge.points: must run from a height of 0 at the bottom to 1 at the top")
+		assert.Contains(t, err.Error(), "line 7: body.edge.points[2]: each point must be higher than the one before it")
+	}
+
+	// Bunched up points make the curve overshoot and come back down
+	_, err = parseSpec(strings.NewReader(`body:
+  edge:
+    profile: spline
+    resolution: 4
+    points:
+      - [0, 0]
+      - [0, 0.9]
+      - [0, 0.95]
+      - [0, 1]
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 5: body.edge.points: the curve between points 1 and 2 dips back down, space the points more evenly")
+	}
+
+	_, err = parseSpec(strings.NewReader(`body:
+  edge:
+    profile: wavy
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `line 3: body.edge.profile: unknown profile "wavy", must be one of chamfer, flat, ogee, round-over, sine, spline, stepped`)
+	}
+}
+
+func TestParseSpecChecksReverse(t *testing.T) {
+	spec, err := parseSpec(strings.NewReader(`reverse:
+  impression: 0.05
+  text:
+    - text: "2026"
+      layout: block
+`), ".")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The reverse takes after the front unless it says otherwise
+	assert.Equal(t, .05, spec.Body.Rim.Border)
+	assert.Equal(t, spec.Body.Rim.Border, spec.Reverse.Rim.Border)
+	if assert.Len(t, spec.Reverse.Text, 1) {
+		assert.Equal(t, .05, spec.Reverse.Text[0].Depth)
+		assert.Equal(t, "sample.ttf", spec.Reverse.Text[0].Font)
+	}
+
+	_, err = parseSpec(strings.NewReader(`reverse:
+  impression: 0.2
+  text:
+    - text: ""
+  logo:
+    path: logo.obj
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: reverse.impression: must be within (0, thickness - impression), got 0.2")
+		assert.Contains(t, err.Error(), "line 4: reverse.text[0].text: text is required")
+		assert.Contains(t, err.Error(), "line 6: reverse.logo.path: only SVG logos can go on the reverse")
+	}
+
+	// Engravings on both sides each fit, but not together
+	_, err = parseSpec(strings.NewReader(`text:
+  - text: Front
+    style: engrave
+    depth: 0.06
+reverse:
+  text:
+    - text: Back
+      style: engrave
+      depth: 0.06
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 5: reverse: engravings on the front and reverse are 0.12 deep between them, must be less than thickness - impression - reverse impression")
+	}
+
+	_, err = parseSpec(strings.NewReader(`text:
+  - text: Front
+    style: engrave
+    depth: 0.15
+reverse: {}
+`), ".")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 4: text[0].depth: must be within (0, thickness - impression - reverse impression), got 0.15")
+	}
+}
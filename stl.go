@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// STL files are Z up while medals are built Y up, so everything written is
+// turned a quarter turn about the X axis to lay the medal flat on the print
+// bed, and turned back when read.
+func toZUp(v vector.Vector3) vector.Vector3 {
+	return vector.NewVector3(v.X(), -v.Z(), v.Y())
+}
+
+func fromZUp(v vector.Vector3) vector.Vector3 {
+	return vector.NewVector3(v.X(), v.Z(), -v.Y())
+}
+
+// stlTriangle is a single facet of an STL file, already Z up.
+type stlTriangle struct {
+	normal   vector.Vector3
+	vertices [3]vector.Vector3
+}
+
+// stlTriangles fans every face of every part into triangles with a normal
+// pointing out of the side the face is wound counter clockwise around.
+// Degenerate triangles are given a zero normal.
+func stlTriangles(parts []medalPart) []stlTriangle {
+	triangles := make([]stlTriangle, 0)
+	for _, part := range parts {
+		for _, face := range part.model.GetFaces() {
+			vertices := face.GetVertices()
+			for i := 1; i+1 < len(vertices); i++ {
+				a := toZUp(vertices[0])
+				b := toZUp(vertices[i])
+				c := toZUp(vertices[i+1])
+
+				normal := b.Sub(a).Cross(c.Sub(a))
+				if normal.Length() > 0 {
+					normal = normal.Normalized()
+				}
+				triangles = append(triangles, stlTriangle{normal: normal, vertices: [3]vector.Vector3{a, b, c}})
+			}
+		}
+	}
+	return triangles
+}
+
+// stlHeader describes the file and the units its coordinates are in.
+func stlHeader(units string) string {
+	return fmt.Sprintf("medal-generation units=%s", units)
+}
+
+// writeBinarySTL writes every part as a single binary STL. The 80 byte
+// header records the units the medal was modelled in.
+func writeBinarySTL(out io.Writer, parts []medalPart, units string) error {
+	if out == nil {
+		return errors.New("Need a writer to write stl to")
+	}
+
+	header := make([]byte, 80)
+	copy(header, stlHeader(units))
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	triangles := stlTriangles(parts)
+	if err := binary.Write(out, binary.LittleEndian, uint32(len(triangles))); err != nil {
+		return err
+	}
+
+	facet := make([]byte, 50)
+	for _, triangle := range triangles {
+		offset := 0
+		put := func(v vector.Vector3) {
+			for _, component := range []float64{v.X(), v.Y(), v.Z()} {
+				binary.LittleEndian.PutUint32(facet[offset:], math.Float32bits(float32(component)))
+				offset += 4
+			}
+		}
+
+		put(triangle.normal)
+		for _, v := range triangle.vertices {
+			put(v)
+		}
+		// Attribute byte count, which nothing we target uses
+		binary.LittleEndian.PutUint16(facet[offset:], 0)
+
+		if _, err := out.Write(facet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeASCIISTL writes every part as a single ASCII STL solid, with the
+// units the medal was modelled in following the solid's name.
+func writeASCIISTL(out io.Writer, parts []medalPart, units string) error {
+	if out == nil {
+		return errors.New("Need a writer to write stl to")
+	}
+
+	if _, err := fmt.Fprintf(out, "solid %s\n", stlHeader(units)); err != nil {
+		return err
+	}
+
+	for _, triangle := range stlTriangles(parts) {
+		n := triangle.normal
+		if _, err := fmt.Fprintf(out, "  facet normal %e %e %e\n    outer loop\n", n.X(), n.Y(), n.Z()); err != nil {
+			return err
+		}
+		for _, v := range triangle.vertices {
+			if _, err := fmt.Fprintf(out, "      vertex %e %e %e\n", v.X(), v.Y(), v.Z()); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(out, "    endloop\n  endfacet\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "endsolid %s\n", stlHeader(units))
+	return err
+}
+
+// readBinarySTL reads every triangle of a binary STL, turned back to Y up.
+func readBinarySTL(in io.Reader) ([][3]vector.Vector3, error) {
+	header := make([]byte, 84)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, fmt.Errorf("unable to read stl header: %w", err)
+	}
+	count := binary.LittleEndian.Uint32(header[80:])
+
+	triangles := make([][3]vector.Vector3, count)
+	facet := make([]byte, 50)
+	for i := range triangles {
+		if _, err := io.ReadFull(in, facet); err != nil {
+			return nil, fmt.Errorf("triangle %d of %d: %w", i+1, count, err)
+		}
+		for corner := range triangles[i] {
+			// Skip over the normal, which is recalculated from winding
+			offset := 12 + (corner * 12)
+			triangles[i][corner] = fromZUp(vector.NewVector3(
+				float64(math.Float32frombits(binary.LittleEndian.Uint32(facet[offset:]))),
+				float64(math.Float32frombits(binary.LittleEndian.Uint32(facet[offset+4:]))),
+				float64(math.Float32frombits(binary.LittleEndian.Uint32(facet[offset+8:]))),
+			))
+		}
+	}
+
+	return triangles, nil
+}
+
+// readASCIISTL reads every triangle of an ASCII STL, turned back to Y up.
+func readASCIISTL(in io.Reader) ([][3]vector.Vector3, error) {
+	triangles := make([][3]vector.Vector3, 0)
+	corners := make([]vector.Vector3, 0, 3)
+
+	scanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "vertex":
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("line %d: expected 3 coordinates for a vertex, got %d", lineNumber, len(fields)-1)
+			}
+			coordinates := make([]float64, 3)
+			for i := range coordinates {
+				parsed, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+				}
+				coordinates[i] = parsed
+			}
+			corners = append(corners, fromZUp(vector.NewVector3(coordinates[0], coordinates[1], coordinates[2])))
+
+		case "endloop":
+			if len(corners) != 3 {
+				return nil, fmt.Errorf("line %d: expected a facet with 3 vertices, got %d", lineNumber, len(corners))
+			}
+			triangles = append(triangles, [3]vector.Vector3{corners[0], corners[1], corners[2]})
+			corners = corners[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return triangles, nil
+}
+
+// isBinarySTL decides whether the STL is binary. ASCII files start with
+// "solid", but so do plenty of binary headers, so a file that's exactly the
+// size its triangle count says it should be is taken as binary regardless.
+func isBinarySTL(data []byte) bool {
+	if len(data) >= 84 {
+		count := binary.LittleEndian.Uint32(data[80:])
+		if uint64(len(data)) == 84+(50*uint64(count)) {
+			return true
+		}
+	}
+
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return !strings.HasPrefix(strings.TrimSpace(string(head)), "solid")
+}
+
+// weldTolerance is how close two vertices of an imported STL have to be to
+// be treated as the same vertex.
+const weldTolerance = 1e-6
+
+// weldVertices merges vertices of the triangles that sit within tolerance
+// of one another, returning every unique vertex and the triangles as
+// indices into them. Triangles that collapse once welded are dropped.
+func weldVertices(triangles [][3]vector.Vector3, tolerance float64) ([]vector.Vector3, [][3]int) {
+	type cell struct{ x, y, z int64 }
+	cellOf := func(v vector.Vector3) cell {
+		return cell{
+			int64(math.Floor(v.X() / tolerance)),
+			int64(math.Floor(v.Y() / tolerance)),
+			int64(math.Floor(v.Z() / tolerance)),
+		}
+	}
+
+	grid := make(map[cell][]int)
+	vertices := make([]vector.Vector3, 0)
+
+	weld := func(v vector.Vector3) int {
+		c := cellOf(v)
+		for x := c.x - 1; x <= c.x+1; x++ {
+			for y := c.y - 1; y <= c.y+1; y++ {
+				for z := c.z - 1; z <= c.z+1; z++ {
+					for _, index := range grid[cell{x, y, z}] {
+						if vertices[index].Distance(v) <= tolerance {
+							return index
+						}
+					}
+				}
+			}
+		}
+
+		vertices = append(vertices, v)
+		grid[c] = append(grid[c], len(vertices)-1)
+		return len(vertices) - 1
+	}
+
+	indices := make([][3]int, 0, len(triangles))
+	for _, triangle := range triangles {
+		welded := [3]int{weld(triangle[0]), weld(triangle[1]), weld(triangle[2])}
+		if welded[0] == welded[1] || welded[1] == welded[2] || welded[2] == welded[0] {
+			continue
+		}
+		indices = append(indices, welded)
+	}
+
+	return vertices, indices
+}
+
+// importSTL reads a binary or ASCII STL into a Y up model, welding together
+// vertices shared between triangles.
+func importSTL(stlStream io.Reader) (*mesh.Model, error) {
+	if stlStream == nil {
+		return nil, errors.New("Need a reader to read stl from")
+	}
+
+	data, err := ioutil.ReadAll(stlStream)
+	if err != nil {
+		return nil, err
+	}
+
+	var triangles [][3]vector.Vector3
+	if isBinarySTL(data) {
+		// The count comes from the file, so it's checked against what's
+		// there before anything is made to hold that many triangles
+		if len(data) < 84 {
+			return nil, fmt.Errorf("binary stl is only %d bytes, too short for its 84 byte header", len(data))
+		}
+		count := uint64(binary.LittleEndian.Uint32(data[80:]))
+		if 84+(50*count) > uint64(len(data)) {
+			return nil, fmt.Errorf("binary stl says it has %d triangles but only has room for %d", count, (len(data)-84)/50)
+		}
+		triangles, err = readBinarySTL(bytes.NewReader(data))
+	} else {
+		triangles, err = readASCIISTL(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, indices := weldVertices(triangles, weldTolerance)
+
+	polys := make([]mesh.Polygon, len(indices))
+	for i, triangle := range indices {
+		corners := []vector.Vector3{vertices[triangle[0]], vertices[triangle[1]], vertices[triangle[2]]}
+		poly, err := mesh.NewPolygon(corners, corners)
+		if err != nil {
+			return nil, err
+		}
+		polys[i] = poly
+	}
+
+	m, err := mesh.NewModel(polys)
+	return &m, err
+}
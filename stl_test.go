@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func stlTestParts(t *testing.T) []medalPart {
+	v := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	model, err := extrudeTriangulation(v, [][3]int32{{0, 1, 2}, {0, 2, 3}}, .5)
+	assert.NoError(t, err)
+	return []medalPart{{name: "block", material: "wood", model: model}}
+}
+
+func assertSameFaces(t *testing.T, expected, actual mesh.Model) {
+	if !assert.Len(t, actual.GetFaces(), len(expected.GetFaces())) {
+		return
+	}
+	for i, face := range expected.GetFaces() {
+		expectedVertices := face.GetVertices()
+		actualVertices := actual.GetFaces()[i].GetVertices()
+		for corner := range expectedVertices {
+			assert.InDelta(t, 0., expectedVertices[corner].Distance(actualVertices[corner]), 1e-6)
+		}
+	}
+}
+
+func TestBinarySTLRoundTrip(t *testing.T) {
+	parts := stlTestParts(t)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeBinarySTL(&out, parts, "mm"))
+	assert.Equal(t, 84+(50*12), out.Len())
+	assert.True(t, strings.HasPrefix(out.String(), "medal-generation units=mm"))
+	assert.Equal(t, uint32(12), binary.LittleEndian.Uint32(out.Bytes()[80:]))
+
+	model, err := importSTL(&out)
+	assert.NoError(t, err)
+	assertSameFaces(t, parts[0].model, *model)
+	assertClosedManifold(t, model.GetFaces())
+	assert.InDelta(t, .5, signedVolume(*model), 1e-6)
+}
+
+func TestASCIISTLRoundTrip(t *testing.T) {
+	parts := stlTestParts(t)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, writeASCIISTL(&out, parts, "in"))
+	assert.True(t, strings.HasPrefix(out.String(), "solid medal-generation units=in\n"))
+	assert.Equal(t, 12, strings.Count(out.String(), "facet normal"))
+
+	model, err := importSTL(&out)
+	assert.NoError(t, err)
+	assertSameFaces(t, parts[0].model, *model)
+}
+
+func TestSTLNormalsFollowWindingAndPointUp(t *testing.T) {
+	// A face pointing up the Y axis should point up the Z axis once written
+	vertices := []vector.Vector3{
+		vector.NewVector3(0, 0, 0),
+		vector.NewVector3(0, 0, 1),
+		vector.NewVector3(1, 0, 0),
+	}
+	poly, err := mesh.NewPolygon(vertices, vertices)
+	assert.NoError(t, err)
+	model, err := mesh.NewModel([]mesh.Polygon{poly})
+	assert.NoError(t, err)
+
+	triangles := stlTriangles([]medalPart{{model: model}})
+	if assert.Len(t, triangles, 1) {
+		assert.InDelta(t, 0., triangles[0].normal.Distance(vector.NewVector3(0, 0, 1)), 1e-9)
+	}
+}
+
+func TestReadASCIISTLReportsLines(t *testing.T) {
+	_, err := readASCIISTL(strings.NewReader(`solid broken
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0
+`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 5")
+	}
+}
+
+func TestImportSTLDetectsBinaryStartingWithSolid(t *testing.T) {
+	out := bytes.Buffer{}
+	assert.NoError(t, writeBinarySTL(&out, stlTestParts(t), "mm"))
+
+	data := out.Bytes()
+	copy(data, "solid but actually binary")
+
+	model, err := importSTL(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, model.GetFaces(), 12)
+}
+
+func TestWeldVertices(t *testing.T) {
+	a := vector.NewVector3(0, 0, 0)
+	b := vector.NewVector3(1, 0, 0)
+	c := vector.NewVector3(1, 1, 0)
+	d := vector.NewVector3(0, 1, 0)
+	nearlyC := vector.NewVector3(1+1e-8, 1, 0)
+
+	vertices, indices := weldVertices([][3]vector.Vector3{
+		{a, b, c},
+		{a, nearlyC, d},
+		// Collapses to a line once welded
+		{c, nearlyC, d},
+	}, weldTolerance)
+
+	assert.Len(t, vertices, 4)
+	assert.Equal(t, [][3]int{{0, 1, 2}, {0, 2, 3}}, indices)
+}
+
+func TestImportSTLRejectsTruncatedBinary(t *testing.T) {
+	out := bytes.Buffer{}
+	assert.NoError(t, writeBinarySTL(&out, stlTestParts(t), "mm"))
+
+	// A count far past the end of the file, which has to be caught before
+	// anything is made to hold that many triangles
+	data := out.Bytes()
+	binary.LittleEndian.PutUint32(data[80:], math.MaxUint32)
+	_, err := importSTL(bytes.NewReader(data))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "says it has 4294967295 triangles but only has room for 12")
+	}
+
+	// Not starting with solid makes it binary, however short it is
+	_, err = importSTL(strings.NewReader("facet normal 0 0 1"))
+	assert.Error(t, err)
+}
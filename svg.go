@@ -0,0 +1,853 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/EliCDavis/mesh"
+	"github.com/EliCDavis/vector"
+)
+
+// svgCurveTolerance is the furthest a flattened SVG curve can stray from the
+// real curve when it isn't known how big the SVG will end up, as a fraction
+// of the size of the document.
+const svgCurveTolerance = .001
+
+// svgTransform is an affine transform stored the same way SVG's matrix()
+// is, mapping (x, y) to (a*x + c*y + e, b*x + d*y + f).
+type svgTransform [6]float64
+
+var svgIdentity = svgTransform{1, 0, 0, 1, 0, 0}
+
+// then applies other after t.
+func (t svgTransform) then(other svgTransform) svgTransform {
+	return svgTransform{
+		other[0]*t[0] + other[2]*t[1],
+		other[1]*t[0] + other[3]*t[1],
+		other[0]*t[2] + other[2]*t[3],
+		other[1]*t[2] + other[3]*t[3],
+		other[0]*t[4] + other[2]*t[5] + other[4],
+		other[1]*t[4] + other[3]*t[5] + other[5],
+	}
+}
+
+func (t svgTransform) apply(p vector.Vector2) vector.Vector2 {
+	return vector.NewVector2(
+		t[0]*p.X()+t[2]*p.Y()+t[4],
+		t[1]*p.X()+t[3]*p.Y()+t[5],
+	)
+}
+
+// scale is the most the transform stretches anything by.
+func (t svgTransform) scale() float64 {
+	return math.Max(math.Hypot(t[0], t[1]), math.Hypot(t[2], t[3]))
+}
+
+var svgTransformPattern = regexp.MustCompile(`([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// parseSVGNumbers reads every number out of a list separated by whitespace
+// and/or commas.
+func parseSVGNumbers(value string) ([]float64, error) {
+	scanner := svgPathScanner{data: value}
+	numbers := make([]float64, 0)
+	for {
+		scanner.skipSeparators()
+		if scanner.done() {
+			return numbers, nil
+		}
+		number, err := scanner.number()
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+}
+
+// parseSVGTransform reads a transform attribute, which is a list of
+// transforms applied right to left.
+func parseSVGTransform(value string) (svgTransform, error) {
+	result := svgIdentity
+	matches := svgTransformPattern.FindAllStringSubmatch(value, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		name := matches[i][1]
+		args, err := parseSVGNumbers(matches[i][2])
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+
+		argCount := func(counts ...int) error {
+			for _, count := range counts {
+				if len(args) == count {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s can't take %d arguments", name, len(args))
+		}
+
+		var t svgTransform
+		switch name {
+		case "matrix":
+			if err := argCount(6); err != nil {
+				return result, err
+			}
+			copy(t[:], args)
+
+		case "translate":
+			if err := argCount(1, 2); err != nil {
+				return result, err
+			}
+			args = append(args, 0)
+			t = svgTransform{1, 0, 0, 1, args[0], args[1]}
+
+		case "scale":
+			if err := argCount(1, 2); err != nil {
+				return result, err
+			}
+			if len(args) == 1 {
+				args = append(args, args[0])
+			}
+			t = svgTransform{args[0], 0, 0, args[1], 0, 0}
+
+		case "rotate":
+			if err := argCount(1, 3); err != nil {
+				return result, err
+			}
+			angle := args[0] * math.Pi / 180
+			cos, sin := math.Cos(angle), math.Sin(angle)
+			t = svgTransform{cos, sin, -sin, cos, 0, 0}
+			if len(args) == 3 {
+				t = svgTransform{1, 0, 0, 1, -args[1], -args[2]}.
+					then(t).
+					then(svgTransform{1, 0, 0, 1, args[1], args[2]})
+			}
+
+		case "skewX":
+			if err := argCount(1); err != nil {
+				return result, err
+			}
+			t = svgTransform{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+
+		case "skewY":
+			if err := argCount(1); err != nil {
+				return result, err
+			}
+			t = svgTransform{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+
+		default:
+			return result, fmt.Errorf("unknown transform %s", name)
+		}
+
+		result = result.then(t)
+	}
+	return result, nil
+}
+
+// svgPathScanner reads the commands, numbers and flags of path data.
+type svgPathScanner struct {
+	data string
+	pos  int
+}
+
+func (s *svgPathScanner) done() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *svgPathScanner) skipSeparators() {
+	for !s.done() && (s.data[s.pos] == ',' || unicode.IsSpace(rune(s.data[s.pos]))) {
+		s.pos++
+	}
+}
+
+// command reads a command letter if there is one next.
+func (s *svgPathScanner) command() (byte, bool) {
+	s.skipSeparators()
+	if s.done() {
+		return 0, false
+	}
+	c := s.data[s.pos]
+	if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		s.pos++
+		return c, true
+	}
+	return 0, false
+}
+
+// hasNumber is whether a number comes next, meaning the last command
+// repeats.
+func (s *svgPathScanner) hasNumber() bool {
+	s.skipSeparators()
+	if s.done() {
+		return false
+	}
+	c := s.data[s.pos]
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.'
+}
+
+func (s *svgPathScanner) number() (float64, error) {
+	s.skipSeparators()
+	start := s.pos
+
+	if !s.done() && (s.data[s.pos] == '-' || s.data[s.pos] == '+') {
+		s.pos++
+	}
+	seenDot := false
+	for !s.done() {
+		c := s.data[s.pos]
+		if c >= '0' && c <= '9' {
+			s.pos++
+		} else if c == '.' && !seenDot {
+			seenDot = true
+			s.pos++
+		} else {
+			break
+		}
+	}
+	if !s.done() && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if !s.done() && (s.data[s.pos] == '-' || s.data[s.pos] == '+') {
+			s.pos++
+		}
+		for !s.done() && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+			s.pos++
+		}
+	}
+
+	value, err := strconv.ParseFloat(s.data[start:s.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number at %q", s.rest())
+	}
+	return value, nil
+}
+
+// flag reads an arc flag, which can be written without anything separating
+// it from what follows.
+func (s *svgPathScanner) flag() (bool, error) {
+	s.skipSeparators()
+	if !s.done() && (s.data[s.pos] == '0' || s.data[s.pos] == '1') {
+		s.pos++
+		return s.data[s.pos-1] == '1', nil
+	}
+	return false, fmt.Errorf("expected a flag at %q", s.rest())
+}
+
+func (s *svgPathScanner) rest() string {
+	rest := s.data[s.pos:]
+	if len(rest) > 10 {
+		rest = rest[:10] + "..."
+	}
+	return rest
+}
+
+// flattenCubic approximates the cubic bézier curve from start to end with
+// line segments that stray no further than tolerance from the curve. The
+// points returned exclude start and include end.
+func flattenCubic(start, control1, control2, end vector.Vector2, tolerance float64) []vector.Vector2 {
+	// The second derivative of a cubic is at most 6 times the largest of
+	// these, and a chord strays at most 1/8 of that over n^2
+	deviation := math.Max(
+		start.Sub(control1.MultByConstant(2)).Add(control2).Length(),
+		control1.Sub(control2.MultByConstant(2)).Add(end).Length(),
+	)
+	segments := 1
+	if tolerance > 0 && deviation > 0 {
+		segments = int(math.Ceil(math.Sqrt((3 * deviation) / (4 * tolerance))))
+	}
+
+	points := make([]vector.Vector2, segments)
+	for i := 1; i <= segments; i++ {
+		t := float64(i) / float64(segments)
+		mt := 1 - t
+		points[i-1] = start.MultByConstant(mt * mt * mt).
+			Add(control1.MultByConstant(3 * mt * mt * t)).
+			Add(control2.MultByConstant(3 * mt * t * t)).
+			Add(end.MultByConstant(t * t * t))
+	}
+	return points
+}
+
+// flattenArc approximates an SVG elliptical arc from start to end. The
+// points returned exclude start and include end.
+func flattenArc(start vector.Vector2, rx, ry, rotation float64, largeArc, sweep bool, end vector.Vector2, tolerance float64) []vector.Vector2 {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || start.Distance(end) == 0 {
+		return []vector.Vector2{end}
+	}
+
+	// Convert from the endpoints SVG uses to a center and angles, following
+	// the SVG implementation notes
+	phi := rotation * math.Pi / 180
+	cosPhi, sinPhi := math.Cos(phi), math.Sin(phi)
+	dx := (start.X() - end.X()) / 2
+	dy := (start.Y() - end.Y()) / 2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	if lambda := (x1*x1)/(rx*rx) + (y1*y1)/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	numerator := (rx * rx * ry * ry) - (rx * rx * y1 * y1) - (ry * ry * x1 * x1)
+	denominator := (rx * rx * y1 * y1) + (ry * ry * x1 * x1)
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if largeArc == sweep {
+		coefficient = -coefficient
+	}
+	cx1 := coefficient * rx * y1 / ry
+	cy1 := coefficient * -ry * x1 / rx
+
+	cx := cosPhi*cx1 - sinPhi*cy1 + (start.X()+end.X())/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (start.Y()+end.Y())/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	step := math.Pi / 2
+	if radius := math.Max(rx, ry); tolerance > 0 && tolerance < radius {
+		step = math.Min(step, 2*math.Acos(1-(tolerance/radius)))
+	}
+	segments := int(math.Max(1, math.Ceil(math.Abs(delta)/step)))
+
+	points := make([]vector.Vector2, segments)
+	for i := 1; i < segments; i++ {
+		a := theta + (delta * float64(i) / float64(segments))
+		points[i-1] = vector.NewVector2(
+			cx+(rx*cosPhi*math.Cos(a))-(ry*sinPhi*math.Sin(a)),
+			cy+(rx*sinPhi*math.Cos(a))+(ry*cosPhi*math.Sin(a)),
+		)
+	}
+	points[segments-1] = end
+	return points
+}
+
+// parseSVGPath flattens path data into a closed contour for every subpath.
+func parseSVGPath(data string, tolerance float64) ([][]vector.Vector2, error) {
+	contours := make([][]vector.Vector2, 0)
+	current := make([]vector.Vector2, 0)
+
+	closeSubpath := func() {
+		if points := dedupePoints(current); len(points) >= 3 {
+			contours = append(contours, points)
+		}
+		current = make([]vector.Vector2, 0)
+	}
+
+	scanner := svgPathScanner{data: data}
+	pen := vector.Vector2Zero()
+	subpathStart := pen
+
+	// The last control point, for the smooth curve commands to reflect
+	var lastControl *vector.Vector2
+	var lastCommand byte
+
+	var command byte
+	for {
+		if c, ok := scanner.command(); ok {
+			command = c
+		} else if scanner.done() {
+			break
+		} else if command == 0 || !scanner.hasNumber() {
+			return nil, fmt.Errorf("expected a command at %q", scanner.rest())
+		}
+
+		relative := command >= 'a'
+		offset := func(p vector.Vector2) vector.Vector2 {
+			if relative {
+				return pen.Add(p)
+			}
+			return p
+		}
+
+		numbers := func(count int) ([]float64, error) {
+			values := make([]float64, count)
+			for i := range values {
+				value, err := scanner.number()
+				if err != nil {
+					return nil, fmt.Errorf("%c: %w", command, err)
+				}
+				values[i] = value
+			}
+			return values, nil
+		}
+		point := func() (vector.Vector2, error) {
+			values, err := numbers(2)
+			if err != nil {
+				return vector.Vector2{}, err
+			}
+			return offset(vector.NewVector2(values[0], values[1])), nil
+		}
+
+		var control *vector.Vector2
+		switch command {
+		case 'M', 'm':
+			p, err := point()
+			if err != nil {
+				return nil, err
+			}
+			closeSubpath()
+			pen, subpathStart = p, p
+			current = append(current, p)
+
+			// Any coordinates following a move are treated as line to
+			if command == 'M' {
+				command = 'L'
+			} else {
+				command = 'l'
+			}
+
+		case 'L', 'l':
+			p, err := point()
+			if err != nil {
+				return nil, err
+			}
+			pen = p
+			current = append(current, p)
+
+		case 'H', 'h', 'V', 'v':
+			values, err := numbers(1)
+			if err != nil {
+				return nil, err
+			}
+			x, y := pen.X(), pen.Y()
+			switch command {
+			case 'H':
+				x = values[0]
+			case 'h':
+				x += values[0]
+			case 'V':
+				y = values[0]
+			case 'v':
+				y += values[0]
+			}
+			pen = vector.NewVector2(x, y)
+			current = append(current, pen)
+
+		case 'C', 'c', 'S', 's':
+			control1 := pen
+			if command == 'S' || command == 's' {
+				if lastControl != nil && strings.ContainsRune("CcSs", rune(lastCommand)) {
+					control1 = pen.MultByConstant(2).Sub(*lastControl)
+				}
+			} else {
+				p, err := point()
+				if err != nil {
+					return nil, err
+				}
+				control1 = p
+			}
+			control2, err := point()
+			if err != nil {
+				return nil, err
+			}
+			end, err := point()
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, flattenCubic(pen, control1, control2, end, tolerance)...)
+			pen = end
+			control = &control2
+
+		case 'Q', 'q', 'T', 't':
+			c := pen
+			if command == 'T' || command == 't' {
+				if lastControl != nil && strings.ContainsRune("QqTt", rune(lastCommand)) {
+					c = pen.MultByConstant(2).Sub(*lastControl)
+				}
+			} else {
+				p, err := point()
+				if err != nil {
+					return nil, err
+				}
+				c = p
+			}
+			end, err := point()
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, flattenQuadratic(pen, c, end, tolerance)...)
+			pen = end
+			control = &c
+
+		case 'A', 'a':
+			values, err := numbers(3)
+			if err != nil {
+				return nil, err
+			}
+			largeArc, err := scanner.flag()
+			if err != nil {
+				return nil, fmt.Errorf("%c: %w", command, err)
+			}
+			sweep, err := scanner.flag()
+			if err != nil {
+				return nil, fmt.Errorf("%c: %w", command, err)
+			}
+			end, err := point()
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, flattenArc(pen, values[0], values[1], values[2], largeArc, sweep, end, tolerance)...)
+			pen = end
+
+		case 'Z', 'z':
+			closeSubpath()
+			pen = subpathStart
+			current = append(current, pen)
+
+		default:
+			return nil, fmt.Errorf("unknown path command %c", command)
+		}
+
+		lastControl = control
+		lastCommand = command
+	}
+
+	// Filled paths are closed whether they say so or not
+	closeSubpath()
+	return contours, nil
+}
+
+// svgFillContours works out which contours of a single SVG element are
+// outer edges of filled areas and which are holes in them, using the
+// element's fill rule. Contours that don't change whether anything is
+// filled, like a contour inside another wound the same way under nonzero,
+// are dropped.
+func svgFillContours(contours [][]vector.Vector2, evenOdd bool) []Outline {
+	direction := make([]int, len(contours))
+	for i, contour := range contours {
+		direction[i] = 1
+		if signedArea(contour) < 0 {
+			direction[i] = -1
+		}
+	}
+
+	type classified struct {
+		index int
+		area  float64
+	}
+	outers := make([]classified, 0)
+	holes := make([]classified, 0)
+
+	for i, contour := range contours {
+		// The winding just outside the contour comes from every contour
+		// around it
+		winding := 0
+		depth := 0
+		for j, other := range contours {
+			if i != j && pointInPolygon(contour[0], other) {
+				winding += direction[j]
+				depth++
+			}
+		}
+
+		var filledInside, filledOutside bool
+		if evenOdd {
+			filledOutside = depth%2 == 1
+			filledInside = !filledOutside
+		} else {
+			filledOutside = winding != 0
+			filledInside = winding+direction[i] != 0
+		}
+
+		area := math.Abs(signedArea(contour))
+		if filledInside && !filledOutside {
+			outers = append(outers, classified{i, area})
+		} else if filledOutside && !filledInside {
+			holes = append(holes, classified{i, area})
+		}
+	}
+
+	// Smallest outers first so holes find the tightest one around them
+	sort.Slice(outers, func(a, b int) bool {
+		return outers[a].area < outers[b].area
+	})
+
+	outlines := make([]Outline, len(outers))
+	for i, outer := range outers {
+		shape, _ := mesh.NewShape(contours[outer.index])
+		outlines[i] = Outline{Outer: windShape(shape, true)}
+	}
+
+	for _, hole := range holes {
+		for i, outer := range outers {
+			if outer.area > hole.area && pointInPolygon(contours[hole.index][0], contours[outer.index]) {
+				shape, _ := mesh.NewShape(contours[hole.index])
+				outlines[i].Holes = append(outlines[i].Holes, windShape(shape, false))
+				break
+			}
+		}
+	}
+
+	return outlines
+}
+
+// svgEllipse is an ellipse flattened into a contour.
+func svgEllipse(cx, cy, rx, ry, tolerance float64) []vector.Vector2 {
+	step := math.Pi / 2
+	if radius := math.Max(rx, ry); tolerance > 0 && tolerance < radius {
+		step = math.Min(step, 2*math.Acos(1-(tolerance/radius)))
+	}
+	segments := int(math.Max(8, math.Ceil(2*math.Pi/step)))
+
+	points := make([]vector.Vector2, segments)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(segments)
+		points[i] = vector.NewVector2(cx+(rx*math.Cos(angle)), cy+(ry*math.Sin(angle)))
+	}
+	return points
+}
+
+// svgStyle is what an element inherits from the groups it's in.
+type svgStyle struct {
+	transform svgTransform
+	evenOdd   bool
+	unfilled  bool
+}
+
+// svgAttributes reads the attributes of an element, letting properties set
+// in its style attribute win over presentation attributes.
+func svgAttributes(element xml.StartElement) map[string]string {
+	attributes := make(map[string]string)
+	for _, attr := range element.Attr {
+		attributes[attr.Name.Local] = strings.TrimSpace(attr.Value)
+	}
+	for _, declaration := range strings.Split(attributes["style"], ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) == 2 {
+			attributes[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return attributes
+}
+
+func svgFloat(attributes map[string]string, name string) (float64, error) {
+	value, ok := attributes[name]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	value = strings.TrimSuffix(value, "px")
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s %q", name, attributes[name])
+	}
+	return parsed, nil
+}
+
+// svgElementContours flattens the shape an element draws, in the element's
+// own coordinates. Elements that don't draw a filled shape have none.
+func svgElementContours(name string, attributes map[string]string, tolerance float64) ([][]vector.Vector2, error) {
+	floats := func(names ...string) ([]float64, error) {
+		values := make([]float64, len(names))
+		for i, name := range names {
+			value, err := svgFloat(attributes, name)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	switch name {
+	case "path":
+		return parseSVGPath(attributes["d"], tolerance)
+
+	case "polygon", "polyline":
+		numbers, err := parseSVGNumbers(attributes["points"])
+		if err != nil {
+			return nil, err
+		}
+		points := make([]vector.Vector2, 0, len(numbers)/2)
+		for i := 0; i+1 < len(numbers); i += 2 {
+			points = append(points, vector.NewVector2(numbers[i], numbers[i+1]))
+		}
+		if points = dedupePoints(points); len(points) < 3 {
+			return nil, nil
+		}
+		return [][]vector.Vector2{points}, nil
+
+	case "circle":
+		values, err := floats("cx", "cy", "r")
+		if err != nil || values[2] <= 0 {
+			return nil, err
+		}
+		return [][]vector.Vector2{svgEllipse(values[0], values[1], values[2], values[2], tolerance)}, nil
+
+	case "ellipse":
+		values, err := floats("cx", "cy", "rx", "ry")
+		if err != nil || values[2] <= 0 || values[3] <= 0 {
+			return nil, err
+		}
+		return [][]vector.Vector2{svgEllipse(values[0], values[1], values[2], values[3], tolerance)}, nil
+
+	case "rect":
+		values, err := floats("x", "y", "width", "height")
+		if err != nil || values[2] <= 0 || values[3] <= 0 {
+			return nil, err
+		}
+		x, y, w, h := values[0], values[1], values[2], values[3]
+		return [][]vector.Vector2{{
+			vector.NewVector2(x, y),
+			vector.NewVector2(x+w, y),
+			vector.NewVector2(x+w, y+h),
+			vector.NewVector2(x, y+h),
+		}}, nil
+	}
+
+	return nil, nil
+}
+
+// svgDocumentSize is the larger side of the document's viewBox, falling
+// back to its width and height.
+func svgDocumentSize(attributes map[string]string) float64 {
+	if numbers, err := parseSVGNumbers(attributes["viewBox"]); err == nil && len(numbers) == 4 {
+		return math.Max(numbers[2], numbers[3])
+	}
+	width, _ := svgFloat(attributes, "width")
+	height, _ := svgFloat(attributes, "height")
+	return math.Max(width, height)
+}
+
+// readSVG turns every filled shape in an SVG into outlines, in the SVG's
+// coordinates with Y pointing down. Curves are flattened to within
+// tolerance, in the SVG's own units, or to within svgCurveTolerance of the
+// size of the document when it's 0.
+func readSVG(in io.Reader, tolerance float64) ([]Outline, error) {
+	decoder := xml.NewDecoder(in)
+
+	outlines := make([]Outline, 0)
+	styles := []svgStyle{{transform: svgIdentity}}
+	absoluteTolerance := tolerance
+	if tolerance == 0 {
+		absoluteTolerance = svgCurveTolerance
+	}
+	seenRoot := false
+
+	// Elements that define things rather than draw them are skipped along
+	// with everything inside of them
+	skipping := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if skipping > 0 {
+				skipping++
+				continue
+			}
+
+			name := element.Name.Local
+			switch name {
+			case "defs", "clipPath", "mask", "symbol", "marker", "pattern", "title", "desc", "metadata", "style":
+				skipping = 1
+				continue
+			}
+
+			attributes := svgAttributes(element)
+			if !seenRoot {
+				if name != "svg" {
+					return nil, fmt.Errorf("expected an svg document, found <%s>", name)
+				}
+				seenRoot = true
+				if size := svgDocumentSize(attributes); size > 0 && tolerance == 0 {
+					absoluteTolerance = svgCurveTolerance * size
+				}
+			}
+
+			style := styles[len(styles)-1]
+			if value, ok := attributes["transform"]; ok {
+				local, err := parseSVGTransform(value)
+				if err != nil {
+					return nil, fmt.Errorf("<%s>: %w", name, err)
+				}
+				style.transform = local.then(style.transform)
+			}
+			if value, ok := attributes["fill-rule"]; ok {
+				style.evenOdd = value == "evenodd"
+			}
+			if value, ok := attributes["fill"]; ok {
+				style.unfilled = value == "none"
+			}
+			if attributes["display"] == "none" {
+				style.unfilled = true
+			}
+			styles = append(styles, style)
+
+			if style.unfilled {
+				continue
+			}
+
+			scale := style.transform.scale()
+			if scale == 0 {
+				continue
+			}
+
+			contours, err := svgElementContours(name, attributes, absoluteTolerance/scale)
+			if err != nil {
+				return nil, fmt.Errorf("<%s>: %w", name, err)
+			}
+			for i, contour := range contours {
+				for j, p := range contour {
+					contours[i][j] = style.transform.apply(p)
+				}
+			}
+			outlines = append(outlines, svgFillContours(contours, style.evenOdd)...)
+
+		case xml.EndElement:
+			if skipping > 0 {
+				skipping--
+				continue
+			}
+			if len(styles) > 1 {
+				styles = styles[:len(styles)-1]
+			}
+		}
+	}
+
+	if !seenRoot {
+		return nil, errors.New("no svg document found")
+	}
+	if len(outlines) == 0 {
+		return nil, errors.New("svg has no filled shapes")
+	}
+	return outlines, nil
+}
+
+// loadSVG reads the filled shapes of the SVG at path, flattening curves to
+// within tolerance the same as readSVG.
+func loadSVG(path string, tolerance float64) ([]Outline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	outlines, err := readSVG(f, tolerance)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import svg %s: %w", path, err)
+	}
+	return outlines, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/EliCDavis/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func contourArea(outline Outline) float64 {
+	area := signedArea(outline.Outer.GetPoints())
+	for _, hole := range outline.Holes {
+		area += signedArea(hole.GetPoints())
+	}
+	return area
+}
+
+func TestParseSVGPathHandlesRelativeAndShorthandCommands(t *testing.T) {
+	contours, err := parseSVGPath("M0,0 h4v3H0z m5 0 l2-0 0 2-2 0Z", 0.01)
+
+	assert.NoError(t, err)
+	if assert.Len(t, contours, 2) {
+		assert.InDelta(t, 12, math.Abs(signedArea(contours[0])), 1e-9)
+		assert.InDelta(t, 4, math.Abs(signedArea(contours[1])), 1e-9)
+		assert.Equal(t, vector.NewVector2(5, 0), contours[1][0])
+	}
+}
+
+func TestParseSVGPathFlattensCurvesWithinTolerance(t *testing.T) {
+	// Two half circle arcs with the flags packed against the numbers, and a
+	// cubic approximation of a quarter circle
+	contours, err := parseSVGPath("M-1 0A1 1 0 011 0A1 1 0 01-1 0Z M0 5 C0.5523 5 1 5.4477 1 6 L0 6Z", 0.001)
+
+	assert.NoError(t, err)
+	if assert.Len(t, contours, 2) {
+		assert.Greater(t, len(contours[0]), 20)
+		for _, p := range contours[0] {
+			assert.InDelta(t, 1, p.Length(), 1e-9)
+		}
+		assert.InDelta(t, math.Pi, math.Abs(signedArea(contours[0])), 0.01)
+		assert.InDelta(t, math.Pi/4, math.Abs(signedArea(contours[1])), 0.01)
+	}
+}
+
+func TestParseSVGPathReportsBadData(t *testing.T) {
+	_, err := parseSVGPath("M0 0 L1", 0.01)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "L: expected a number")
+	}
+
+	_, err = parseSVGPath("M0 0 X1 1", 0.01)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown path command X")
+	}
+}
+
+func TestParseSVGTransformAppliesRightToLeft(t *testing.T) {
+	transform, err := parseSVGTransform("translate(10, 0) scale(2) rotate(90)")
+
+	assert.NoError(t, err)
+	p := transform.apply(vector.NewVector2(1, 0))
+	assert.InDelta(t, 10, p.X(), 1e-9)
+	assert.InDelta(t, 2, p.Y(), 1e-9)
+
+	pivoted, err := parseSVGTransform("rotate(180 1 1)")
+	assert.NoError(t, err)
+	p = pivoted.apply(vector.Vector2Zero())
+	assert.InDelta(t, 2, p.X(), 1e-9)
+	assert.InDelta(t, 2, p.Y(), 1e-9)
+
+	_, err = parseSVGTransform("spin(3)")
+	assert.EqualError(t, err, "unknown transform spin")
+}
+
+func TestSVGFillRulesFindHoles(t *testing.T) {
+	square := func(size float64) []vector.Vector2 {
+		return []vector.Vector2{
+			vector.NewVector2(-size, -size),
+			vector.NewVector2(size, -size),
+			vector.NewVector2(size, size),
+			vector.NewVector2(-size, size),
+		}
+	}
+
+	// Wound the same way, nonzero fills the inner square while evenodd cuts
+	// it out
+	sameWay := [][]vector.Vector2{square(2), square(1)}
+	nonZero := svgFillContours(sameWay, false)
+	if assert.Len(t, nonZero, 1) {
+		assert.Empty(t, nonZero[0].Holes)
+		assert.InDelta(t, 16, contourArea(nonZero[0]), 1e-9)
+	}
+
+	evenOdd := svgFillContours(sameWay, true)
+	if assert.Len(t, evenOdd, 1) {
+		assert.Len(t, evenOdd[0].Holes, 1)
+		assert.InDelta(t, 12, contourArea(evenOdd[0]), 1e-9)
+	}
+
+	// Wound opposite ways, nonzero cuts the inner square out too
+	opposite := [][]vector.Vector2{square(2), reversePoints(square(1))}
+	nonZero = svgFillContours(opposite, false)
+	if assert.Len(t, nonZero, 1) {
+		assert.Len(t, nonZero[0].Holes, 1)
+	}
+
+	// An island inside the hole is its own outline
+	islands := svgFillContours([][]vector.Vector2{square(3), square(2), square(1)}, true)
+	assert.Len(t, islands, 2)
+}
+
+func TestReadSVG(t *testing.T) {
+	outlines, err := readSVG(strings.NewReader(`<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+  <defs>
+    <rect width="100" height="100"/>
+  </defs>
+  <g transform="translate(50 50)" style="fill-rule:evenodd">
+    <path d="M-40-40H40V40H-40Z M-20-20H20V20H-20Z"/>
+    <circle r="10" fill="none" stroke="black"/>
+  </g>
+  <polygon points="0,0 10,0 0,10" transform="scale(2)"/>
+  <circle cx="90" cy="90" r="5"/>
+</svg>`), 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, outlines, 3) {
+		assert.Len(t, outlines[0].Holes, 1)
+		assert.InDelta(t, 6400-1600, contourArea(outlines[0]), 1e-6)
+		assert.InDelta(t, 200, contourArea(outlines[1]), 1e-6)
+		// Flattened to within a tenth of a unit of the circle, a thousandth
+		// of the size of the document
+		assert.InEpsilon(t, 25*math.Pi, contourArea(outlines[2]), 0.05)
+
+		center := outlinesCenter(outlines[:1])
+		assert.InDelta(t, 50, center.X(), 1e-9)
+		assert.InDelta(t, 50, center.Y(), 1e-9)
+	}
+}
+
+func TestReadSVGReportsProblems(t *testing.T) {
+	_, err := readSVG(strings.NewReader(`<html></html>`), 0)
+	assert.EqualError(t, err, "expected an svg document, found <html>")
+
+	_, err = readSVG(strings.NewReader(`<svg><path d="M0 0 L1 0 L1 1" fill="none"/></svg>`), 0)
+	assert.EqualError(t, err, "svg has no filled shapes")
+
+	_, err = readSVG(strings.NewReader(`<svg><circle r="big"/></svg>`), 0)
+	assert.EqualError(t, err, "<circle>: unable to parse r \"big\"")
+}
+
+func TestPlaceEmblemScalesLongestSide(t *testing.T) {
+	outlines, err := readSVG(strings.NewReader(`<svg viewBox="0 0 40 20"><rect x="0" y="0" width="40" height="20"/></svg>`), 0)
+	assert.NoError(t, err)
+
+	placed := placeEmblem(outlines, logoSpec{Scale: 0.5, Offset: [2]float64{0.1, 0.2}})
+	bottomLeft, topRight := outlinesBounds(placed)
+	assert.InDelta(t, -0.15, bottomLeft.X(), 1e-9)
+	assert.InDelta(t, 0.35, topRight.X(), 1e-9)
+	assert.InDelta(t, 0.075, bottomLeft.Y(), 1e-9)
+	assert.InDelta(t, 0.325, topRight.Y(), 1e-9)
+}
+
+func TestPlaceEmblemReadsLikeTheSVG(t *testing.T) {
+	// A triangle with its right angle in the top left corner of the SVG
+	outlines, err := readSVG(strings.NewReader(`<svg viewBox="0 0 10 10"><polygon points="0,0 10,0 0,10"/></svg>`), 0)
+	assert.NoError(t, err)
+
+	// Looking down at the face X points left and Y points up
+	placed := placeEmblem(outlines, logoSpec{Scale: 0.5})
+	closest := math.Inf(1)
+	for _, p := range placed[0].Outer.GetPoints() {
+		closest = math.Min(closest, p.Distance(vector.NewVector2(0.25, 0.25)))
+	}
+	assert.InDelta(t, 0, closest, 1e-9)
+	assert.Greater(t, signedArea(placed[0].Outer.GetPoints()), 0.)
+}
+
+func TestReadSVGFollowsCurvesToWithinTolerance(t *testing.T) {
+	document := `<svg viewBox="0 0 100 100"><circle cx="50" cy="50" r="40"/></svg>`
+	rough, err := readSVG(strings.NewReader(document), 0)
+	assert.NoError(t, err)
+	fine, err := readSVG(strings.NewReader(document), .001)
+	assert.NoError(t, err)
+
+	points := fine[0].Outer.GetPoints()
+	assert.Greater(t, len(points), len(rough[0].Outer.GetPoints()))
+	for i, p := range points {
+		middle := p.Add(points[(i+1)%len(points)]).MultByConstant(.5)
+		assert.LessOrEqual(t, 40-middle.Distance(vector.NewVector2(50, 50)), .001+1e-9)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+
+	"github.com/EliCDavis/vector"
+)
+
+// maxProfileResolution is the most segments a single curve of a profile is
+// ever broken into, however fine the quality asks for.
+const maxProfileResolution = 256
+
+// minCircleSides is the fewest sides a circle is ever drawn with.
+const minCircleSides = 8
+
+// maxAngleRadians is the quality's max angle in radians.
+func (q qualitySpec) maxAngleRadians() float64 {
+	return q.MaxAngle * math.Pi / 180
+}
+
+// arcSegments is how many straight lines an arc of the given radius
+// sweeping through the angle in radians is broken into, so no line strays
+// further than the tolerance from the arc or turns through more than the max
+// angle.
+func (q qualitySpec) arcSegments(radius, sweep float64) int {
+	step := q.maxAngleRadians()
+	if radius > q.Tolerance {
+		// A chord across an angle a strays radius * (1 - cos(a / 2)) from
+		// its arc
+		step = math.Min(step, 2*math.Acos(1-(q.Tolerance/radius)))
+	}
+
+	// Shaves off floating point error so sweeps that divide evenly don't
+	// gain a segment
+	return int(math.Max(1, math.Ceil((math.Abs(sweep)/step)-1e-9)))
+}
+
+// circleSides is how many sides a circle of the given radius is drawn
+// with.
+func (q qualitySpec) circleSides(radius float64) int {
+	sides := q.arcSegments(radius, 2*math.Pi)
+	if sides < minCircleSides {
+		return minCircleSides
+	}
+	return sides
+}
+
+// curveTolerance is how far a flattened letter curve can stray from the
+// real curve, in the units of text that's scaled by scale.
+func (q qualitySpec) curveTolerance(scale float64) float64 {
+	return q.Tolerance / scale
+}
+
+// profileResolution is the fewest segments each curve of the profile can be
+// broken into while staying within the tolerance of the real curve and
+// turning no more than the max angle at every point. Corners the profile is
+// meant to have are left sharp.
+func (q qualitySpec) profileResolution(profile edgeProfile, edge edgeSpec, thickness float64) int {
+	for resolution := 1; resolution < maxProfileResolution; resolution *= 2 {
+		edge.Resolution = resolution
+		coarse := profile.points(edge, thickness)
+
+		// The profile drawn much finer stands in for the real curve
+		edge.Resolution = resolution * 4
+		fine := profile.points(edge, thickness)
+
+		deviation := 0.
+		for _, p := range fine {
+			deviation = math.Max(deviation, distanceToPolyline(p, coarse))
+		}
+
+		if deviation <= q.Tolerance && maxTurn(coarse) <= math.Max(q.maxAngleRadians(), maxTurn(fine)+1e-9) {
+			return resolution
+		}
+	}
+	return maxProfileResolution
+}
+
+// distanceToPolyline is how far the point is from the closest segment of
+// the open polyline.
+func distanceToPolyline(point vector.Vector2, polyline []vector.Vector2) float64 {
+	if len(polyline) == 1 {
+		return point.Distance(polyline[0])
+	}
+
+	closest := math.Inf(1)
+	for i := 1; i < len(polyline); i++ {
+		closest = math.Min(closest, distanceToContour(point, polyline[i-1:i+1]))
+	}
+	return closest
+}
+
+// maxTurn is the sharpest turn in radians the polyline takes from one
+// segment to the next.
+func maxTurn(polyline []vector.Vector2) float64 {
+	sharpest := 0.
+	for i := 2; i < len(polyline); i++ {
+		before := polyline[i-1].Sub(polyline[i-2])
+		after := polyline[i].Sub(polyline[i-1])
+		if before.Length() == 0 || after.Length() == 0 {
+			continue
+		}
+		cos := before.Dot(after) / (before.Length() * after.Length())
+		sharpest = math.Max(sharpest, math.Acos(math.Max(-1, math.Min(1, cos))))
+	}
+	return sharpest
+}
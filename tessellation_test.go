@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircleSidesFollowTheSizeOfTheMedal(t *testing.T) {
+	quality := defaultMedalSpec().Quality
+
+	// The default medal keeps the 64 sides it's always had
+	assert.Equal(t, 64, quality.circleSides(1.1))
+
+	// Big medals need more sides to stay within the tolerance
+	sides := quality.circleSides(50)
+	assert.Greater(t, sides, 64)
+	step := 2 * math.Pi / float64(sides)
+	assert.LessOrEqual(t, 50*(1-math.Cos(step/2)), quality.Tolerance)
+
+	// And small ones need fewer once the angle allows it
+	coarse := qualitySpec{Tolerance: .005, MaxAngle: 15}
+	assert.Equal(t, 24, coarse.circleSides(.2))
+	assert.Equal(t, minCircleSides, qualitySpec{Tolerance: 10, MaxAngle: 90}.circleSides(1))
+}
+
+func TestProfileResolutionStaysWithinTolerance(t *testing.T) {
+	quality := qualitySpec{Tolerance: .005, MaxAngle: 90}
+	edge := edgeSpec{Size: .1}
+	profile := edgeProfiles["sine"]
+
+	resolution := quality.profileResolution(profile, edge, .3)
+	edge.Resolution = resolution
+	coarse := profile.points(edge, .3)
+	edge.Resolution = 100
+	for _, p := range profile.points(edge, .3) {
+		assert.LessOrEqual(t, distanceToPolyline(p, coarse), quality.Tolerance+1e-4)
+	}
+
+	// Asking for smoother turns takes more segments
+	smooth := qualitySpec{Tolerance: .005, MaxAngle: 5}
+	assert.Greater(t, smooth.profileResolution(profile, edgeSpec{Size: .1}, .3), resolution)
+
+	// Corners the profile means to have don't need any more
+	assert.Equal(t, 1, smooth.profileResolution(edgeProfiles["chamfer"], edgeSpec{Size: .02}, .3))
+}
+
+func TestTextCurveToleranceFollowsTheQuality(t *testing.T) {
+	spec := defaultMedalSpec()
+	spec.Text = []textSpec{{Text: "Aleatha", Scale: .5}, {Text: "Singleton", CurveTolerance: .02}}
+	spec.applyDefaults()
+
+	assert.InDelta(t, spec.Quality.Tolerance/.5, spec.Text[0].CurveTolerance, 1e-12)
+	assert.Equal(t, .02, spec.Text[1].CurveTolerance)
+}